@@ -0,0 +1,339 @@
+package thread
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrRunTimeout is returned by Runnables created with WithTimeout if the inner
+// Runnable exceeded its time limit.
+var ErrRunTimeout = errors.New("Runnable exceeded its time limit")
+
+// Default size of the read buffer used by ReaderLoop
+const readerLoopBufSize = 4096
+
+// ConsumeOption configures the Runnable returned by Consume.
+type ConsumeOption func(*consumeConfig)
+
+// Configuration shared by all consumer types
+type consumeConfig struct {
+	drainOnStop bool
+}
+
+// DrainOnStop makes the consumer handle all items still buffered in its input
+// channel once stopped, instead of returning immediately and leaving them in
+// the channel. Draining never waits for new items, it ends as soon as the
+// channel is empty.
+func DrainOnStop() ConsumeOption {
+	return func(c *consumeConfig) {
+		c.drainOnStop = true
+	}
+}
+
+// Consume returns a Runnable which calls handle for every item received from
+// in. Its Run method returns once in is closed, handle returns an error or the
+// Thread is stopped, whichever happens first.
+func Consume[T any](in <-chan T, handle func(T) error, opts ...ConsumeOption) Runnable {
+	c := &consumer[T]{in: in, handle: handle}
+	for _, opt := range opts {
+		opt(&c.consumeConfig)
+	}
+	return c
+}
+
+// Runnable returned by Consume
+type consumer[T any] struct {
+	consumeConfig
+	in     <-chan T
+	handle func(T) error
+}
+
+func (c *consumer[T]) Run(stop chan bool) error {
+	for {
+		select {
+		case <-stop:
+			if c.drainOnStop {
+				return c.drain()
+			}
+			return nil
+		case item, ok := <-c.in:
+			if !ok {
+				return nil
+			}
+			if err := c.handle(item); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Internal helper handling all buffered items without blocking
+func (c *consumer[T]) drain() error {
+	for {
+		select {
+		case item, ok := <-c.in:
+			if !ok {
+				return nil
+			}
+			if err := c.handle(item); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// Produce returns a Runnable which repeatedly calls next and sends the returned
+// item to out. Its Run method returns once next reports ok=false or an error,
+// or the Thread is stopped. Stopping also interrupts a pending send, so a full
+// out channel never blocks shutdown. The out channel is not closed by Run.
+func Produce[T any](out chan<- T, next func() (item T, ok bool, err error)) Runnable {
+	return &producer[T]{out: out, next: next}
+}
+
+// Runnable returned by Produce
+type producer[T any] struct {
+	out  chan<- T
+	next func() (T, bool, error)
+}
+
+func (p *producer[T]) Run(stop chan bool) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		item, ok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		select {
+		case <-stop:
+			return nil
+		case p.out <- item:
+		}
+	}
+}
+
+// Pipe connects a producer and a consumer Thread via a new channel with a
+// buffer of buf items and starts both of them. The producer calls produce like
+// Produce and closes the channel once its run returned, whether it ran out of
+// items, failed or has been stopped. The consumer calls consume for every item
+// like Consume with DrainOnStop and returns once the channel is closed and all
+// items have been handled, so stopping the producer shuts down the whole
+// pipeline. The pipeline is meant to run once: as the channel stays closed,
+// the producer must not be started again.
+func Pipe[T any](produce func() (T, bool, error), consume func(T) error, buf int) (prod, cons *Thread, ch chan T) {
+	ch = make(chan T, buf)
+	cons = New(Consume(ch, consume, DrainOnStop())).Start()
+	prod = New(&closingProducer[T]{producer[T]{out: ch, next: produce}}).Start()
+	return prod, cons, ch
+}
+
+// Runnable used by Pipe, closing the channel of the producer once it returned
+type closingProducer[T any] struct {
+	producer[T]
+}
+
+func (p *closingProducer[T]) Run(stop chan bool) error {
+	defer close(p.out)
+	return p.producer.Run(stop)
+}
+
+// ReaderLoop returns a Runnable which reads chunks of up to bufSize bytes from r
+// and calls handle for each of them. A bufSize of zero or less selects a
+// default size. The buffer is reused, so handle must not retain the slice. Its
+// Run method returns nil on EOF or once stopped, and the error otherwise.
+//
+// As Read blocks, stopping alone cannot interrupt a pending read. Readers
+// implementing SetReadDeadline(time.Time) error, such as net.Conn and os.File,
+// are unblocked automatically by setting a deadline in the past on stop, which
+// is cleared again before Run returns, so the reader can be used by later runs.
+// For any other reader the caller must close it after calling Stop().
+func ReaderLoop(r io.Reader, bufSize int, handle func([]byte) error) Runnable {
+	if bufSize <= 0 {
+		bufSize = readerLoopBufSize
+	}
+	return &readerLoop{r: r, bufSize: bufSize, handle: handle}
+}
+
+// Implemented by readers which support read deadlines
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Runnable returned by ReaderLoop
+type readerLoop struct {
+	r       io.Reader
+	bufSize int
+	handle  func([]byte) error
+}
+
+func (l *readerLoop) Run(stop chan bool) error {
+	if deadliner, ok := l.r.(readDeadliner); ok {
+		// interrupt a pending read on stop
+		done := make(chan struct{})
+		interrupted := false
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-stop:
+				deadliner.SetReadDeadline(time.Now())
+				interrupted = true
+			case <-done:
+			}
+		}()
+		defer func() {
+			close(done)
+			wg.Wait()
+			// clear the deadline for the next run
+			if interrupted {
+				deadliner.SetReadDeadline(time.Time{})
+			}
+		}()
+	}
+	buf := make([]byte, l.bufSize)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		n, err := l.r.Read(buf)
+		if n > 0 {
+			if err := l.handle(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			// a read interrupted by stopping is not an error
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
+		}
+	}
+}
+
+// PeriodicOption configures the Runnable returned by Periodic.
+type PeriodicOption func(*periodic)
+
+// OnTick sets a callback receiving the timing of every tick: the time the tick
+// was scheduled for, the time it actually fired, how long fn ran and its
+// result. It is called on the Thread's goroutine, right after fn returned.
+func OnTick(callback func(scheduled, actual time.Time, dur time.Duration, err error)) PeriodicOption {
+	return func(p *periodic) {
+		p.onTick = callback
+	}
+}
+
+// Periodic returns a Runnable which calls fn once every interval until the
+// Thread is stopped or fn returns an error, which is then returned by Run. The
+// ticks are scheduled relative to the start of the run, ticks missed because fn
+// took longer than the interval are skipped. An interval of zero or less calls
+// fn back-to-back, checking for a stop in between.
+func Periodic(interval time.Duration, fn func() error, opts ...PeriodicOption) Runnable {
+	p := &periodic{interval: interval, fn: fn}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Runnable returned by Periodic
+type periodic struct {
+	interval time.Duration
+	fn       func() error
+	onTick   func(scheduled, actual time.Time, dur time.Duration, err error)
+}
+
+func (p *periodic) Run(stop chan bool) error {
+	scheduled := time.Now().Add(p.interval)
+	for {
+		if p.interval <= 0 {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+			scheduled = time.Now()
+		} else if !Sleep(stop, time.Until(scheduled)) {
+			return nil
+		}
+		actual := time.Now()
+		err := p.fn()
+		if p.onTick != nil {
+			p.onTick(scheduled, actual, time.Since(actual), err)
+		}
+		if err != nil {
+			return err
+		}
+		if p.interval <= 0 {
+			continue
+		}
+		// skip the ticks missed while fn was running
+		scheduled = scheduled.Add(p.interval)
+		if now := time.Now(); scheduled.Before(now) {
+			missed := now.Sub(scheduled)/p.interval + 1
+			scheduled = scheduled.Add(missed * p.interval)
+		}
+	}
+}
+
+// WithTimeout returns a Runnable running the given one, but signaling it to
+// stop once a run takes longer than d, in which case ErrRunTimeout is returned
+// instead of the inner result. The inner Runnable must observe its stop
+// channel for the time limit to actually interrupt it. Combined with automatic
+// restarts this restarts overrunning runs.
+func WithTimeout(runnable Runnable, d time.Duration) Runnable {
+	return &timeoutRunnable{runnable: runnable, timeout: d}
+}
+
+// Runnable returned by WithTimeout
+type timeoutRunnable struct {
+	runnable Runnable
+	timeout  time.Duration
+}
+
+func (r *timeoutRunnable) Run(stop chan bool) error {
+	// derive the inner stop channel, closed on stop or timeout
+	inner := make(chan bool)
+	done := make(chan struct{})
+	var timedOut bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(r.timeout)
+		defer timer.Stop()
+		select {
+		case <-stop:
+		case <-timer.C:
+			timedOut = true
+		case <-done:
+			return
+		}
+		close(inner)
+	}()
+	err := r.runnable.Run(inner)
+	close(done)
+	wg.Wait()
+	if timedOut {
+		return ErrRunTimeout
+	}
+	return err
+}
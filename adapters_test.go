@@ -0,0 +1,343 @@
+package thread
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	in := make(chan int, 3)
+	handled := []int{}
+	thread := New(Consume(in, func(item int) error {
+		handled = append(handled, item)
+		return nil
+	}))
+	thread.Start()
+
+	for i := 1; i <= 3; i++ {
+		in <- i
+	}
+	close(in)
+	thread.Join()
+
+	if len(handled) != 3 || handled[0] != 1 || handled[1] != 2 || handled[2] != 3 {
+		t.Fatalf("expected items [1 2 3] to be handled, got %v", handled)
+	}
+}
+
+func TestConsumeHandlerError(t *testing.T) {
+	in := make(chan int, 2)
+	in <- 1
+	in <- 2
+	errHandle := errors.New("handle failed")
+	err := Consume(in, func(item int) error {
+		return errHandle
+	}).Run(make(chan bool))
+	if err != errHandle {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if len(in) != 1 {
+		t.Fatalf("expected consumer to stop after the first item, %d items left", len(in))
+	}
+}
+
+func TestConsumeDrainOnStop(t *testing.T) {
+	in := make(chan int, 10)
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	handled := 0
+	thread := New(Consume(in, func(item int) error {
+		if item == 0 {
+			// block the first item until more are buffered and stop was called
+			close(handling)
+			<-release
+		}
+		handled++
+		return nil
+	}, DrainOnStop()))
+	thread.Start()
+
+	in <- 0
+	<-handling
+	for i := 1; i < 10; i++ {
+		in <- i
+	}
+	thread.Stop()
+	close(release)
+	thread.Join()
+
+	if handled != 10 {
+		t.Fatalf("expected all 10 items to be handled, got %d", handled)
+	}
+	if len(in) != 0 {
+		t.Fatalf("expected input to be drained, %d items left", len(in))
+	}
+}
+
+// Returns a next function for Produce yielding 0, 1, ... up to n-1
+func sequence(n int) func() (int, bool, error) {
+	i := 0
+	return func() (int, bool, error) {
+		if i >= n {
+			return 0, false, nil
+		}
+		i++
+		return i - 1, true, nil
+	}
+}
+
+func TestProduce(t *testing.T) {
+	out := make(chan int, 5)
+	thread := New(Produce(out, sequence(5)))
+	thread.Start()
+	thread.Join()
+	close(out)
+
+	expected := 0
+	for item := range out {
+		if item != expected {
+			t.Fatalf("expected item %d, got %d", expected, item)
+		}
+		expected++
+	}
+	if expected != 5 {
+		t.Fatalf("expected 5 items, got %d", expected)
+	}
+}
+
+func TestProduceStopMidStream(t *testing.T) {
+	out := make(chan int)
+	thread := New(Produce(out, sequence(100)))
+	thread.Start()
+
+	for i := 0; i < 3; i++ {
+		if item := <-out; item != i {
+			t.Fatalf("expected item %d, got %d", i, item)
+		}
+	}
+	// nobody receives anymore, the pending send must not block the stop
+	thread.Stop()
+	thread.Join()
+}
+
+func TestPipe(t *testing.T) {
+	var sum int
+	prod, cons, ch := Pipe(sequence(100), func(item int) error {
+		sum += item
+		return nil
+	}, 4)
+	if cap(ch) != 4 {
+		t.Fatalf("expected a buffer of 4 items, got %d", cap(ch))
+	}
+	if err := prod.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the consumer ends on its own once all items have been handled
+	if err := cons.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sum != 4950 {
+		t.Fatalf("expected all items to be consumed, got sum %d", sum)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("expected the channel to be closed")
+	}
+}
+
+func TestPipeStop(t *testing.T) {
+	var consumed atomic.Int32
+	prod, cons, _ := Pipe(func() (int, bool, error) {
+		return 0, true, nil
+	}, func(int) error {
+		consumed.Add(1)
+		return nil
+	}, 8)
+	eventually(t, func() bool { return consumed.Load() > 0 })
+	// stopping the producer shuts down the whole pipeline
+	prod.Stop()
+	prod.Join()
+	if err := cons.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	thread := New(WithTimeout(&blockingRunnable{}, 20*time.Millisecond))
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, ErrRunTimeout) {
+		t.Fatalf("expected ErrRunTimeout, got %v", err)
+	}
+
+	// runs finishing in time keep their result
+	if err := WithTimeout(&returnRunnable{err: errTemporary}, time.Second).Run(make(chan bool)); err != errTemporary {
+		t.Fatalf("expected inner error, got %v", err)
+	}
+
+	// a regular stop is passed through
+	thread = New(WithTimeout(&blockingRunnable{}, time.Second))
+	thread.Start()
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected stopped run to succeed, got %v", err)
+	}
+}
+
+func TestReaderLoop(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+		w.Close()
+	}()
+	var received bytes.Buffer
+	thread := New(ReaderLoop(r, 4, func(chunk []byte) error {
+		if len(chunk) > 4 {
+			t.Errorf("expected chunks of at most 4 bytes, got %d", len(chunk))
+		}
+		received.Write(chunk)
+		return nil
+	}))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected EOF to end the loop cleanly, got %v", err)
+	}
+	if received.String() != "hello world" {
+		t.Fatalf("expected %q, got %q", "hello world", received.String())
+	}
+}
+
+func TestReaderLoopError(t *testing.T) {
+	r, w := io.Pipe()
+	go w.Write([]byte("data"))
+	errHandle := errors.New("handle failed")
+	thread := New(ReaderLoop(r, 0, func(chunk []byte) error {
+		return errHandle
+	}))
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, errHandle) {
+		t.Fatalf("expected the handle error, got %v", err)
+	}
+}
+
+func TestReaderLoopStopClose(t *testing.T) {
+	r, _ := io.Pipe()
+	thread := New(ReaderLoop(r, 0, func(chunk []byte) error { return nil }))
+	thread.Start()
+	time.Sleep(10 * time.Millisecond)
+	thread.Stop()
+	r.Close()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error after stop, got %v", err)
+	}
+}
+
+func TestReaderLoopStopDeadline(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+	received := make(chan string, 1)
+	thread := New(ReaderLoop(conn, 0, func(chunk []byte) error {
+		received <- string(chunk)
+		return nil
+	}))
+	thread.Start()
+	time.Sleep(10 * time.Millisecond)
+	if err := thread.StopAndJoin(); err != nil {
+		t.Fatalf("expected the deadline to interrupt the read, got %v", err)
+	}
+	// the deadline has been cleared, so a restarted loop reads as usual
+	thread.Start()
+	go peer.Write([]byte("again"))
+	select {
+	case chunk := <-received:
+		if chunk != "again" {
+			t.Fatalf("expected %q, got %q", "again", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the restarted loop to read, state %s, error %v", thread.State(), thread.LastError())
+	}
+	if err := thread.StopAndJoin(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// tick records the arguments of an OnTick callback
+type tick struct {
+	scheduled, actual time.Time
+	dur               time.Duration
+	err               error
+}
+
+func TestPeriodic(t *testing.T) {
+	ticks := make(chan tick, 100)
+	calls := 0
+	thread := New(Periodic(5*time.Millisecond, func() error {
+		calls++
+		time.Sleep(time.Millisecond)
+		return nil
+	}, OnTick(func(scheduled, actual time.Time, dur time.Duration, err error) {
+		ticks <- tick{scheduled, actual, dur, err}
+	})))
+	thread.Start()
+	var received []tick
+	for len(received) < 4 {
+		select {
+		case tick := <-ticks:
+			received = append(received, tick)
+		case <-time.After(time.Second):
+			t.Fatalf("expected 4 ticks, got %d", len(received))
+		}
+	}
+	thread.StopAndJoin()
+
+	for i, tick := range received {
+		if i > 0 && !tick.scheduled.After(received[i-1].scheduled) {
+			t.Fatalf("tick %d: expected increasing scheduled times", i)
+		}
+		if tick.actual.Before(tick.scheduled) {
+			t.Fatalf("tick %d: fired %v before its schedule", i, tick.scheduled.Sub(tick.actual))
+		}
+		if tick.dur < time.Millisecond || tick.err != nil {
+			t.Fatalf("tick %d: unexpected duration %v or error %v", i, tick.dur, tick.err)
+		}
+	}
+	if calls < 4 {
+		t.Fatalf("expected at least 4 calls, got %d", calls)
+	}
+}
+
+func TestPeriodicBackToBack(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		var calls atomic.Int32
+		thread := New(Periodic(interval, func() error {
+			calls.Add(1)
+			return nil
+		})).Start()
+		eventually(t, func() bool { return calls.Load() >= 100 })
+		if err := thread.StopAndJoin(); err != nil {
+			t.Fatalf("interval %v: expected no error, got %v", interval, err)
+		}
+	}
+}
+
+func TestPeriodicError(t *testing.T) {
+	errTick := errors.New("tick failed")
+	var reported error
+	thread := New(Periodic(time.Millisecond, func() error {
+		return errTick
+	}, OnTick(func(scheduled, actual time.Time, dur time.Duration, err error) {
+		reported = err
+	})))
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, errTick) {
+		t.Fatalf("expected the error of fn, got %v", err)
+	}
+	if reported != errTick {
+		t.Fatalf("expected OnTick to receive the error, got %v", reported)
+	}
+}
@@ -0,0 +1,18 @@
+package thread
+
+// WithCPUAffinity pins each run of the Thread to the given set of CPUs, e.g.
+// for cache locality on NUMA systems. The goroutine executing the Runnable is
+// locked to its OS thread via runtime.LockOSThread for the duration of the run
+// and the affinity of that OS thread is restricted to cpus, restoring the
+// previous affinity once the run returns. If the affinity cannot be applied,
+// e.g. because cpus contains no CPU available to the process, the run fails
+// with the error of the system call.
+//
+// Affinity is only supported on Linux, on other platforms the option is a
+// no-op. Goroutines started by the Runnable are not pinned.
+func WithCPUAffinity(cpus []int) Option {
+	cpus = append([]int(nil), cpus...)
+	return func(t *Thread) {
+		t.cpus = cpus
+	}
+}
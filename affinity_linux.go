@@ -0,0 +1,50 @@
+package thread
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Internal CPU set of sched_setaffinity(2), sized like the CPU_SETSIZE of glibc
+type cpuSet [1024 / 64]uint64
+
+// Internal helper locking the calling goroutine to its OS thread and pinning
+// the latter to cpus. The returned function restores the previous affinity and
+// unlocks the goroutine again.
+func pinCPUs(cpus []int) (func(), error) {
+	runtime.LockOSThread()
+	var previous, set cpuSet
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &previous); err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= len(set)*64 {
+			runtime.UnlockOSThread()
+			return nil, syscall.EINVAL
+		}
+		set[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+	if err := schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &set); err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	return func() {
+		if schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &previous) != nil {
+			// keep the thread locked, so that the runtime discards it once the
+			// goroutine exits rather than reusing it with the wrong affinity
+			return
+		}
+		runtime.UnlockOSThread()
+	}, nil
+}
+
+// Internal helper getting or setting the affinity of the calling OS thread
+func schedAffinity(trap uintptr, set *cpuSet) error {
+	_, _, errno := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*set), uintptr(unsafe.Pointer(set)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package thread
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// affinityRunnable records the CPU set of its OS thread
+type affinityRunnable struct {
+	set atomic.Pointer[cpuSet]
+}
+
+func (r *affinityRunnable) Run(stop chan bool) error {
+	var set cpuSet
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &set); err != nil {
+		return err
+	}
+	r.set.Store(&set)
+	return nil
+}
+
+func TestCPUAffinity(t *testing.T) {
+	var available cpuSet
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &available); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// pin to the first CPU available to the test
+	cpu := -1
+	for i := 0; i < len(available)*64 && cpu < 0; i++ {
+		if available[i/64]&(1<<(uint(i)%64)) != 0 {
+			cpu = i
+		}
+	}
+	runnable := &affinityRunnable{}
+	if err := New(runnable, WithCPUAffinity([]int{cpu})).RunBlocking(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var expected cpuSet
+	expected[cpu/64] = 1 << (uint(cpu) % 64)
+	if set := runnable.set.Load(); *set != expected {
+		t.Fatalf("expected the run to be pinned to CPU %d, got mask %x", cpu, *set)
+	}
+	// the previous affinity has been restored before unlocking the OS thread
+	var restored cpuSet
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &restored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored != available {
+		t.Fatalf("expected affinity %x to be restored, got %x", available, restored)
+	}
+	if err := New(runnable, WithCPUAffinity([]int{-1})).RunBlocking(); err == nil {
+		t.Fatal("expected an invalid CPU to fail the run")
+	}
+}
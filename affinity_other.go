@@ -0,0 +1,9 @@
+//go:build !linux
+
+package thread
+
+// Internal helper pinning the calling goroutine to cpus, a no-op as CPU
+// affinity is only supported on Linux
+func pinCPUs(cpus []int) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,96 @@
+package thread
+
+import (
+	"math"
+	"time"
+)
+
+// BackoffStrategy computes the delay before an automatic restart. NextDelay is
+// passed the number of consecutive failed runs, starting at 1. Reset is called
+// on Start() and after every run that returned nil. Both are called with the
+// Thread locked, so they must not call back into the Thread.
+type BackoffStrategy interface {
+	NextDelay(attempt int) time.Duration
+	Reset()
+}
+
+// WithBackoffStrategy sets the strategy computing the delays between automatic
+// restarts, replacing the exponential backoff configured by WithAutoRestart().
+// A nil strategy restores the default.
+func WithBackoffStrategy(strategy BackoffStrategy) Option {
+	return func(t *Thread) {
+		t.strategy = strategy
+	}
+}
+
+// ConstantBackoff waits the same Delay before every restart.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay returns Delay regardless of the attempt.
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return b.Delay
+}
+
+// Reset does nothing, ConstantBackoff is stateless.
+func (b ConstantBackoff) Reset() {}
+
+// ExponentialBackoff starts at Initial and doubles the delay with every
+// attempt, up to Max. A Max of zero or less means no upper bound.
+type ExponentialBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// NextDelay returns Initial * 2^(attempt-1), capped at Max. Without Max the
+// delay saturates at the largest time.Duration.
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < attempt && delay > 0 && (b.Max <= 0 || delay < b.Max); i++ {
+		if delay > math.MaxInt64/2 {
+			// saturate instead of overflowing into a negative delay
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+	}
+	return capBackoff(delay, b.Max)
+}
+
+// Reset does nothing, ExponentialBackoff is stateless.
+func (b ExponentialBackoff) Reset() {}
+
+// FibonacciBackoff grows the delay along the Fibonacci sequence in multiples of
+// Unit, i.e. 1, 1, 2, 3, 5, ... times Unit, up to Max. A Max of zero or less
+// means no upper bound.
+type FibonacciBackoff struct {
+	Unit time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns Unit times the attempt-th Fibonacci number, capped at Max.
+// Without Max the delay saturates at the largest time.Duration.
+func (b FibonacciBackoff) NextDelay(attempt int) time.Duration {
+	prev, delay := time.Duration(0), b.Unit
+	for i := 1; i < attempt && delay > 0 && (b.Max <= 0 || delay < b.Max); i++ {
+		if delay > math.MaxInt64-prev {
+			// saturate instead of overflowing into a negative delay
+			delay = math.MaxInt64
+			break
+		}
+		prev, delay = delay, prev+delay
+	}
+	return capBackoff(delay, b.Max)
+}
+
+// Reset does nothing, FibonacciBackoff is stateless.
+func (b FibonacciBackoff) Reset() {}
+
+// Internal helper limiting a delay to max, unless max is zero or less
+func capBackoff(delay, max time.Duration) time.Duration {
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
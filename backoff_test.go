@@ -0,0 +1,83 @@
+package thread
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testBackoffSequence(t *testing.T, strategy BackoffStrategy, expected []time.Duration) {
+	t.Helper()
+	for i, want := range expected {
+		if got := strategy.NextDelay(i + 1); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	ms := time.Millisecond
+	testBackoffSequence(t, ConstantBackoff{Delay: 5 * ms}, []time.Duration{5 * ms, 5 * ms, 5 * ms, 5 * ms})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	ms := time.Millisecond
+	testBackoffSequence(t, ExponentialBackoff{Initial: ms, Max: 10 * ms},
+		[]time.Duration{ms, 2 * ms, 4 * ms, 8 * ms, 10 * ms, 10 * ms})
+	testBackoffSequence(t, ExponentialBackoff{Initial: ms},
+		[]time.Duration{ms, 2 * ms, 4 * ms, 8 * ms, 16 * ms, 32 * ms})
+}
+
+func TestFibonacciBackoff(t *testing.T) {
+	ms := time.Millisecond
+	testBackoffSequence(t, FibonacciBackoff{Unit: ms, Max: 6 * ms},
+		[]time.Duration{ms, ms, 2 * ms, 3 * ms, 5 * ms, 6 * ms, 6 * ms})
+	testBackoffSequence(t, FibonacciBackoff{Unit: ms},
+		[]time.Duration{ms, ms, 2 * ms, 3 * ms, 5 * ms, 8 * ms, 13 * ms})
+}
+
+func TestBackoffSaturation(t *testing.T) {
+	// large attempt counts must not overflow into negative delays
+	const max = time.Duration(math.MaxInt64)
+	if got := (ExponentialBackoff{Initial: time.Second}).NextDelay(40); got != max {
+		t.Errorf("exponential: expected %v, got %v", max, got)
+	}
+	if got := (FibonacciBackoff{Unit: time.Second}).NextDelay(60); got != max {
+		t.Errorf("fibonacci: expected %v, got %v", max, got)
+	}
+	if got := (FibonacciBackoff{Unit: time.Second, Max: time.Hour}).NextDelay(1000); got != time.Hour {
+		t.Errorf("fibonacci with max: expected %v, got %v", time.Hour, got)
+	}
+}
+
+type recordingBackoff struct {
+	attempts []int
+	resets   atomic.Int32
+}
+
+func (b *recordingBackoff) NextDelay(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return 0
+}
+
+func (b *recordingBackoff) Reset() {
+	b.resets.Add(1)
+}
+
+func TestWithBackoffStrategy(t *testing.T) {
+	strategy := &recordingBackoff{}
+	runnable := &countRunnable{errs: []error{errTemporary, errTemporary, nil}}
+	thread := New(runnable, WithAutoRestart(-1, time.Hour), WithBackoffStrategy(strategy))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(strategy.attempts) != 2 || strategy.attempts[0] != 1 || strategy.attempts[1] != 2 {
+		t.Fatalf("expected attempts [1 2], got %v", strategy.attempts)
+	}
+	// reset on start and after the final healthy run
+	if resets := strategy.resets.Load(); resets != 2 {
+		t.Fatalf("expected 2 resets, got %d", resets)
+	}
+}
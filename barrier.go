@@ -0,0 +1,59 @@
+package thread
+
+import "sync"
+
+// Barrier synchronizes a fixed number of Runnables, none of which proceeds
+// before all of them reached the barrier, e.g. for coordinated initialization.
+// It is integrated with the stop signal of the Thread and reusable, once
+// released the next n calls to Wait form a new round.
+type Barrier struct {
+	mutex   sync.Mutex
+	n       int
+	waiting int
+	release chan struct{}
+}
+
+// NewBarrier creates a Barrier released once n parties are waiting on it.
+func NewBarrier(n int) *Barrier {
+	return &Barrier{n: n, release: make(chan struct{})}
+}
+
+// Wait blocks until n parties are waiting and returns true, or returns false
+// once stop is closed before the barrier has been released. A party giving up
+// no longer counts towards the current round.
+func (b *Barrier) Wait(stop chan bool) bool {
+	b.mutex.Lock()
+	release := b.release
+	b.waiting++
+	if b.waiting >= b.n {
+		// last one to arrive, release everyone and start a new round
+		close(release)
+		b.waiting = 0
+		b.release = make(chan struct{})
+		b.mutex.Unlock()
+		return true
+	}
+	b.mutex.Unlock()
+	select {
+	case <-release:
+		return true
+	case <-stop:
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	select {
+	case <-release:
+		// released concurrently with the stop
+		return true
+	default:
+		b.waiting--
+		return false
+	}
+}
+
+// Waiting returns the number of parties currently waiting on the Barrier.
+func (b *Barrier) Waiting() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.waiting
+}
@@ -0,0 +1,64 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+// barrierRunnable waits on a barrier and reports whether it passed
+type barrierRunnable struct {
+	barrier *Barrier
+	passed  chan bool
+}
+
+func (r *barrierRunnable) Run(stop chan bool) error {
+	r.passed <- r.barrier.Wait(stop)
+	<-stop
+	return nil
+}
+
+func TestBarrier(t *testing.T) {
+	barrier := NewBarrier(3)
+	passed := make(chan bool, 3)
+	threads := make([]*Thread, 3)
+	for i := range threads {
+		threads[i] = New(&barrierRunnable{barrier: barrier, passed: passed})
+	}
+	defer func() {
+		for _, thread := range threads {
+			thread.Stop()
+			thread.Join()
+		}
+	}()
+	threads[0].Start()
+	threads[1].Start()
+	for barrier.Waiting() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// stopping one party releases it without releasing the others
+	threads[0].Stop()
+	if <-passed {
+		t.Fatal("expected stopped party not to pass the barrier")
+	}
+	if n := barrier.Waiting(); n != 1 {
+		t.Fatalf("expected 1 party to remain waiting, got %d", n)
+	}
+	threads[2].Start()
+	select {
+	case <-passed:
+		t.Fatal("expected barrier to wait for a third party")
+	case <-time.After(20 * time.Millisecond):
+	}
+	threads[0].Join()
+	threads[0].Start()
+	for i := 0; i < 3; i++ {
+		select {
+		case ok := <-passed:
+			if !ok {
+				t.Fatal("expected all parties to pass the barrier")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected barrier to be released")
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package thread
+
+// Capabilities lists the optional interfaces implemented by a Runnable.
+type Capabilities struct {
+	Context   bool // ContextRunnable
+	Readiness bool // ReadyRunnable
+	Health    bool // HealthChecker
+	Quiesce   bool // Quiescable
+	Control   bool // Controllable
+}
+
+// Capabilities returns the optional interfaces implemented by the Thread's
+// Runnable, allowing callers to adapt their behaviour to it.
+func (t *Thread) Capabilities() Capabilities {
+	t.mutex.Lock()
+	runnable := t.runnable
+	t.mutex.Unlock()
+	return capabilitiesOf(runnable)
+}
+
+// Internal helper checking which optional interfaces runnable implements
+func capabilitiesOf(runnable Runnable) Capabilities {
+	var caps Capabilities
+	_, caps.Context = runnable.(ContextRunnable)
+	_, caps.Readiness = runnable.(ReadyRunnable)
+	_, caps.Health = runnable.(HealthChecker)
+	_, caps.Quiesce = runnable.(Quiescable)
+	_, caps.Control = runnable.(Controllable)
+	return caps
+}
@@ -0,0 +1,24 @@
+package thread
+
+import (
+	"testing"
+)
+
+// readyHealthRunnable implements ReadyRunnable and HealthChecker
+type readyHealthRunnable struct {
+	readyRunnable
+}
+
+func (r *readyHealthRunnable) Healthy() bool {
+	return true
+}
+
+func TestCapabilities(t *testing.T) {
+	expected := Capabilities{Readiness: true, Health: true}
+	if caps := New(&readyHealthRunnable{}).Capabilities(); caps != expected {
+		t.Fatalf("expected %+v, got %+v", expected, caps)
+	}
+	if caps := New(&blockingRunnable{}).Capabilities(); caps != (Capabilities{}) {
+		t.Fatalf("expected no capabilities, got %+v", caps)
+	}
+}
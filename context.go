@@ -0,0 +1,101 @@
+package thread
+
+import (
+	"context"
+)
+
+// ContextRunnable may be implemented by a Runnable in addition to its Run
+// method. The Thread then calls RunContext instead of Run, passing a context
+// that is cancelled once the Thread is stopped. The cancellation cause, as
+// reported by context.Cause(ctx), tells why:
+//
+//   - ErrStopped if Thread.Stop() was called
+//   - context.DeadlineExceeded if the parent context's deadline passed
+//   - the parent's cause if the parent context was cancelled
+type ContextRunnable interface {
+	RunContext(ctx context.Context) error
+}
+
+// NewWithContext creates a new Thread like New, deriving the context of each
+// run from ctx. Once ctx is done, a running Thread is stopped.
+func NewWithContext(ctx context.Context, runnable Runnable, opts ...Option) *Thread {
+	t := New(runnable, opts...)
+	t.ctx = ctx
+	return t
+}
+
+// HybridRunnable is a Runnable during the migration from stop channels to
+// contexts, using both at the same time. Its Run method cannot satisfy
+// Runnable, so wrap it with Hybrid for use with a Thread.
+type HybridRunnable interface {
+	Run(ctx context.Context, stop chan bool) error
+}
+
+// Hybrid adapts a HybridRunnable to Runnable. Both the context and the stop
+// channel are driven together: once the Thread is stopped or the context is
+// cancelled for any other reason, the stop channel is closed as well.
+func Hybrid(runnable HybridRunnable) Runnable {
+	return &hybridRunnable{runnable: runnable}
+}
+
+// Runnable returned by Hybrid
+type hybridRunnable struct {
+	runnable HybridRunnable
+}
+
+// Run is only called outside of a Thread, which calls RunContext, so derive
+// the context from the stop channel
+func (r *hybridRunnable) Run(stop chan bool) error {
+	ctx, release := stopContext(stop)
+	defer release()
+	return r.runnable.Run(ctx, stop)
+}
+
+func (r *hybridRunnable) RunContext(ctx context.Context) error {
+	stop := make(chan bool)
+	unregister := context.AfterFunc(ctx, func() {
+		close(stop)
+	})
+	defer unregister()
+	return r.runnable.Run(ctx, stop)
+}
+
+// FromFunc adapts an existing function taking a context to Runnable, without
+// writing the select loop on the stop channel. The context passed to fn is
+// cancelled once the Thread is stopped, just like for a ContextRunnable.
+func FromFunc(fn func(ctx context.Context) error) Runnable {
+	return funcRunnable(fn)
+}
+
+// Runnable returned by FromFunc
+type funcRunnable func(ctx context.Context) error
+
+// Run is only called outside of a Thread, which calls RunContext, so derive
+// the context from the stop channel
+func (fn funcRunnable) Run(stop chan bool) error {
+	ctx, release := stopContext(stop)
+	defer release()
+	return fn(ctx)
+}
+
+func (fn funcRunnable) RunContext(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// Internal helper returning a context cancelled with ErrStopped once stop is
+// closed, release must be called once the context is not needed anymore
+func stopContext(stop chan bool) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			cancel(ErrStopped)
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
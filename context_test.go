@@ -0,0 +1,125 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// contextRunnable waits for its context to be done and records the cause
+type contextRunnable struct {
+	cause error
+}
+
+func (r *contextRunnable) Run(stop chan bool) error {
+	panic("Run must not be called for a ContextRunnable")
+}
+
+func (r *contextRunnable) RunContext(ctx context.Context) error {
+	<-ctx.Done()
+	r.cause = context.Cause(ctx)
+	return nil
+}
+
+func TestContextCauseStop(t *testing.T) {
+	runnable := &contextRunnable{}
+	thread := New(runnable)
+	thread.Start()
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runnable.cause != ErrStopped {
+		t.Fatalf("expected cause ErrStopped, got %v", runnable.cause)
+	}
+}
+
+func TestContextCauseParentCancelled(t *testing.T) {
+	errShutdown := errors.New("shutdown")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	runnable := &contextRunnable{}
+	thread := NewWithContext(ctx, runnable)
+	thread.Start()
+	cancel(errShutdown)
+	thread.Join()
+	if runnable.cause != errShutdown {
+		t.Fatalf("expected parent cause, got %v", runnable.cause)
+	}
+}
+
+func TestContextCauseDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	runnable := &contextRunnable{}
+	thread := NewWithContext(ctx, runnable)
+	thread.Start()
+	thread.Join()
+	if runnable.cause != context.DeadlineExceeded {
+		t.Fatalf("expected cause DeadlineExceeded, got %v", runnable.cause)
+	}
+}
+
+func TestParentContextStopsRunnable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	thread := NewWithContext(ctx, &blockingRunnable{})
+	thread.Start()
+	cancel()
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected cancelled parent context to stop the thread")
+	}
+}
+
+// hybridExitRunnable exits via its context or its stop channel
+type hybridExitRunnable struct {
+	viaContext bool
+	cause      error
+}
+
+func (r *hybridExitRunnable) Run(ctx context.Context, stop chan bool) error {
+	if r.viaContext {
+		<-ctx.Done()
+	} else {
+		<-stop
+	}
+	r.cause = context.Cause(ctx)
+	return nil
+}
+
+func TestHybridContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runnable := &hybridExitRunnable{viaContext: true}
+	thread := NewWithContext(ctx, Hybrid(runnable))
+	thread.Start()
+	cancel()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runnable.cause != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", runnable.cause)
+	}
+}
+
+func TestHybridStop(t *testing.T) {
+	runnable := &hybridExitRunnable{}
+	thread := New(Hybrid(runnable))
+	thread.Start()
+	if err := thread.StopAndJoin(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runnable.cause != ErrStopped {
+		t.Fatalf("expected the context to be cancelled with ErrStopped, got %v", runnable.cause)
+	}
+}
+
+func TestHybridOutsideThread(t *testing.T) {
+	runnable := &hybridExitRunnable{viaContext: true}
+	stop := make(chan bool)
+	time.AfterFunc(10*time.Millisecond, func() { close(stop) })
+	if err := Hybrid(runnable).Run(stop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runnable.cause != ErrStopped {
+		t.Fatalf("expected the stop channel to cancel the context, got %v", runnable.cause)
+	}
+}
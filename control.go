@@ -0,0 +1,60 @@
+package thread
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotControllable is returned by Thread.Send if the Runnable does not
+	// implement Controllable.
+	ErrNotControllable = errors.New("Runnable does not accept control messages")
+	// ErrNotRunning is returned by Thread.Send and Thread.StackTrace if the
+	// Thread is not running, and the panic value of Thread.Stop in strict mode.
+	ErrNotRunning = errors.New("Thread is not running")
+)
+
+// Control is a message sent to a running Runnable, e.g. to reload its
+// configuration. The meaning of Command and Payload is up to the Runnable.
+type Control struct {
+	Command string
+	Payload interface{}
+}
+
+// Controllable may be implemented by a Runnable accepting control messages
+// while running. Control is called from the sending goroutine, so it must be
+// safe for concurrent use with Run, and should return quickly.
+type Controllable interface {
+	Control(ctrl Control) error
+}
+
+// Send passes the control message to the running Runnable and returns its
+// result. It returns ErrNotControllable if the Runnable does not implement
+// Controllable and ErrNotRunning if the Thread is not running.
+func (t *Thread) Send(ctrl Control) error {
+	t.mutex.Lock()
+	state, runnable := t.state, t.runnable
+	t.mutex.Unlock()
+	controllable, ok := runnable.(Controllable)
+	if !ok {
+		return ErrNotControllable
+	}
+	if state != RUNNING {
+		return ErrNotRunning
+	}
+	// deliver outside of the mutex, the runnable may take a while
+	return controllable.Control(ctrl)
+}
+
+// Broadcast sends the control message to every member whose Runnable
+// implements Controllable and returns the errors in member order. Members not
+// implementing Controllable are skipped and get a nil entry.
+func (g *Group) Broadcast(ctrl Control) []error {
+	threads := g.members()
+	errs := make([]error, len(threads))
+	for i, t := range threads {
+		if err := t.Send(ctrl); err != ErrNotControllable {
+			errs[i] = err
+		}
+	}
+	return errs
+}
@@ -0,0 +1,79 @@
+package thread
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// controlRunnable records the control messages it received
+type controlRunnable struct {
+	blockingRunnable
+	mutex    sync.Mutex
+	received []Control
+}
+
+func (r *controlRunnable) Control(ctrl Control) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.received = append(r.received, ctrl)
+	return nil
+}
+
+func (r *controlRunnable) commands() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var commands []string
+	for _, ctrl := range r.received {
+		commands = append(commands, ctrl.Command)
+	}
+	return commands
+}
+
+func TestSend(t *testing.T) {
+	runnable := &controlRunnable{}
+	thread := New(runnable)
+	if err := thread.Send(Control{Command: "reload"}); err != ErrNotRunning {
+		t.Fatalf("expected ErrNotRunning, got %v", err)
+	}
+	thread.Start()
+	defer thread.StopAndJoin()
+	thread.WaitState(RUNNING, time.Second)
+	if err := thread.Send(Control{Command: "reload", Payload: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if commands := runnable.commands(); len(commands) != 1 || commands[0] != "reload" {
+		t.Fatalf("expected a reload, got %v", commands)
+	}
+	if err := New(&blockingRunnable{}).Send(Control{}); err != ErrNotControllable {
+		t.Fatalf("expected ErrNotControllable, got %v", err)
+	}
+}
+
+func TestGroupBroadcast(t *testing.T) {
+	g := NewGroup()
+	first, second, stopped := &controlRunnable{}, &controlRunnable{}, &controlRunnable{}
+	g.Spawn(first)
+	g.Spawn(&blockingRunnable{})
+	g.Spawn(second)
+	g.Add(New(stopped))
+	g.WaitRunning(3, time.Second)
+
+	errs := g.Broadcast(Control{Command: "reload"})
+	if len(errs) != 4 || errs[0] != nil || errs[1] != nil || errs[2] != nil || errs[3] != ErrNotRunning {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for i, runnable := range []*controlRunnable{first, second} {
+		if commands := runnable.commands(); len(commands) != 1 || commands[0] != "reload" {
+			t.Fatalf("member %d: expected a reload, got %v", i, commands)
+		}
+	}
+	if commands := stopped.commands(); len(commands) != 0 {
+		t.Fatalf("expected the stopped member not to receive anything, got %v", commands)
+	}
+
+	g.StopAll()
+	for _, member := range g.members()[:3] {
+		member.Join()
+	}
+}
@@ -0,0 +1,75 @@
+package thread
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrIdleTimeout is returned by Thread.StopWhenIdle if in-flight work did not
+// complete within the timeout.
+var ErrIdleTimeout = errors.New("Thread did not become idle in time")
+
+// Begin marks the start of an in-flight work item, such as a request, and
+// returns true, or returns false without counting the item if the Thread no
+// longer accepts work due to StopWhenIdle. Every successful Begin must be
+// followed by an End once the item completed. The Runnable calls both on its
+// own Thread.
+func (t *Thread) Begin() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.draining {
+		return false
+	}
+	t.inflight++
+	return true
+}
+
+// End marks the completion of an in-flight work item started via Begin. Ending
+// more items than begun panics.
+func (t *Thread) End() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.inflight == 0 {
+		panic("thread: End without Begin")
+	}
+	t.inflight--
+	if t.inflight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Inflight returns the number of work items begun but not ended yet.
+func (t *Thread) Inflight() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.inflight
+}
+
+// StopWhenIdle stops the Thread gracefully: it stops accepting new work, so
+// that Begin returns false, waits for the in-flight work items to complete and
+// then stops the Thread. If the items did not complete within timeout, the
+// Thread is stopped regardless and ErrIdleTimeout is returned. A restart
+// accepts work again. Use Join to wait for the Thread to terminate.
+func (t *Thread) StopWhenIdle(timeout time.Duration) error {
+	t.mutex.Lock()
+	t.draining = true
+	idle := t.idle
+	if idle == nil && t.inflight > 0 {
+		idle = make(chan struct{})
+		t.idle = idle
+	}
+	t.mutex.Unlock()
+	var err error
+	if idle != nil {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-idle:
+		case <-timer.C:
+			err = ErrIdleTimeout
+		}
+		timer.Stop()
+	}
+	t.stop(false)
+	return err
+}
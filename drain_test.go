@@ -0,0 +1,83 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+// requestRunnable serves requests from its channel, each taking a while
+type requestRunnable struct {
+	thread   *Thread
+	requests chan time.Duration
+	rejected chan struct{}
+}
+
+func (r *requestRunnable) Run(stop chan bool) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		case d := <-r.requests:
+			if !r.thread.Begin() {
+				r.rejected <- struct{}{}
+				continue
+			}
+			go func() {
+				defer r.thread.End()
+				time.Sleep(d)
+			}()
+		}
+	}
+}
+
+func TestStopWhenIdle(t *testing.T) {
+	runnable := &requestRunnable{requests: make(chan time.Duration), rejected: make(chan struct{}, 1)}
+	thread := New(runnable)
+	runnable.thread = thread
+	thread.Start()
+	runnable.requests <- 30 * time.Millisecond
+	runnable.requests <- 50 * time.Millisecond
+	eventually(t, func() bool { return thread.Inflight() == 2 })
+	result := make(chan error)
+	go func() {
+		result <- thread.StopWhenIdle(time.Second)
+	}()
+	for thread.Begin() {
+		thread.End()
+		time.Sleep(time.Millisecond)
+	}
+	runnable.requests <- time.Millisecond
+	<-runnable.rejected
+	if thread.State() != RUNNING {
+		t.Fatal("expected thread to keep running while draining")
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("expected requests to drain in time, got %v", err)
+	}
+	if n := thread.Inflight(); n != 0 {
+		t.Fatalf("expected no requests in flight, got %d", n)
+	}
+	thread.Join()
+}
+
+func TestStopWhenIdleTimeout(t *testing.T) {
+	runnable := &requestRunnable{requests: make(chan time.Duration), rejected: make(chan struct{}, 1)}
+	thread := New(runnable)
+	runnable.thread = thread
+	thread.Start()
+	runnable.requests <- 100 * time.Millisecond
+	eventually(t, func() bool { return thread.Inflight() == 1 })
+	if err := thread.StopWhenIdle(10 * time.Millisecond); err != ErrIdleTimeout {
+		t.Fatalf("expected ErrIdleTimeout, got %v", err)
+	}
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a restart accepts work again
+	thread.Start()
+	defer thread.StopAndJoin()
+	if !thread.Begin() {
+		t.Fatal("expected restarted thread to accept work")
+	}
+	thread.End()
+}
@@ -0,0 +1,71 @@
+package thread
+
+import (
+	"fmt"
+)
+
+// ExitReason describes why a run of a Thread ended.
+type ExitReason uint8
+
+const (
+	// ExitCompleted means the Runnable returned nil on its own
+	ExitCompleted ExitReason = iota
+	// ExitStopped means the Runnable returned after being signaled to stop
+	ExitStopped
+	// ExitError means the Runnable returned an error
+	ExitError
+	// ExitPanic means the Runnable panicked
+	ExitPanic
+	// ExitKilled means the run was abandoned via Thread.Kill()
+	ExitKilled
+)
+
+func (r ExitReason) String() string {
+	switch r {
+	case ExitCompleted:
+		return "completed"
+	case ExitStopped:
+		return "stopped"
+	case ExitError:
+		return "error"
+	case ExitPanic:
+		return "panic"
+	case ExitKilled:
+		return "killed"
+	}
+	return fmt.Sprintf("ExitReason(%d)", uint8(r))
+}
+
+// ThreadError is returned by Thread.Join() if a run failed. It carries the
+// context of the failure for structured logging and unwraps to the original
+// error, so errors.Is and errors.As work as usual.
+type ThreadError struct {
+	Name       string     // name of the Thread
+	Reason     ExitReason // why the run ended
+	Attempt    int        // attempt number within the lifecycle, starting at 1
+	RunID      uint64     // ID of the failed run, see Thread.CurrentRunID()
+	InstanceID uint64     // ID of the Thread, see Thread.InstanceID()
+	Err        error      // the original error
+}
+
+func (e *ThreadError) Error() string {
+	return fmt.Sprintf("Thread %q failed in attempt %d (%s): %v", e.Name, e.Attempt, e.Reason, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *ThreadError) Unwrap() error {
+	return e.Err
+}
+
+// Fields returns the context of the error as key/value pairs for structured
+// loggers.
+func (e *ThreadError) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"thread":   e.Name,
+		"reason":   e.Reason.String(),
+		"attempt":  e.Attempt,
+		"run":      e.RunID,
+		"instance": e.InstanceID,
+		"error":    e.Err,
+	}
+}
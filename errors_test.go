@@ -0,0 +1,42 @@
+package thread
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThreadError(t *testing.T) {
+	thread := New(&countRunnable{errs: []error{errTemporary, errTemporary}},
+		WithName("worker"),
+		WithAutoRestart(1, time.Millisecond),
+	)
+	thread.Start()
+	err := thread.Join()
+
+	var threadErr *ThreadError
+	if !errors.As(err, &threadErr) {
+		t.Fatalf("expected a *ThreadError, got %v", err)
+	}
+	if threadErr.Name != "worker" || threadErr.Reason != ExitError || threadErr.Attempt != 2 || threadErr.RunID != 2 {
+		t.Fatalf("expected fields to be populated, got %+v", threadErr)
+	}
+	if !errors.Is(err, errTemporary) {
+		t.Fatal("expected error to unwrap to the original error")
+	}
+	if fields := threadErr.Fields(); fields["thread"] != "worker" || fields["attempt"] != 2 || fields["reason"] != "error" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
+
+func TestThreadErrorPanic(t *testing.T) {
+	thread := New(&panicRunnable{value: "boom"})
+	thread.Start()
+	err := thread.Join()
+
+	var threadErr *ThreadError
+	var panicErr *PanicError
+	if !errors.As(err, &threadErr) || threadErr.Reason != ExitPanic || !errors.As(err, &panicErr) {
+		t.Fatalf("expected a *ThreadError wrapping a *PanicError, got %v", err)
+	}
+}
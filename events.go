@@ -0,0 +1,96 @@
+package thread
+
+// OverflowPolicy determines which events are dropped if the buffer of the
+// Events() channel is full.
+type OverflowPolicy uint8
+
+const (
+	// DropNewest discards the event that does not fit into the buffer anymore
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the new one
+	DropOldest
+)
+
+// WithEventBuffer sets the size of the buffer backing the Events() channel and
+// the policy applied once it is full. Either way, publishing events never
+// blocks the Thread.
+func WithEventBuffer(size int, policy OverflowPolicy) Option {
+	return func(t *Thread) {
+		t.eventBuffer = size
+		t.overflow = policy
+	}
+}
+
+// DroppedEvents returns the number of events dropped due to a full buffer of
+// the Events() channel since the Thread was last started.
+func (t *Thread) DroppedEvents() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.dropped
+}
+
+// Internal helper publishing an event according to the overflow policy, must be
+// called with the mutex held
+func (t *Thread) emitLocked(event Event) {
+	select {
+	case t.events <- event:
+		return
+	default:
+	}
+	t.dropped++
+	if t.overflow == DropOldest {
+		// make room, a concurrent receiver may have done so already
+		select {
+		case <-t.events:
+		default:
+		}
+		select {
+		case t.events <- event:
+		default:
+		}
+	}
+}
+
+// WithOnStateChange sets a hook called with the previous and the new state on
+// every state change, a simpler alternative to Events() if all you need is a
+// callback. The hook runs outside of the mutex in a separate goroutine, one
+// call at a time and in the order of the transitions, so it may lag behind
+// the current state, but never blocks the Thread.
+func WithOnStateChange(hook func(old, new State)) Option {
+	return func(t *Thread) {
+		t.onStateChange = hook
+	}
+}
+
+// Internal record of a state change pending delivery to the hook
+type stateChange struct {
+	old, new State
+}
+
+// Internal helper queueing a state change for the hook and starting the
+// dispatcher unless it is active already, must be called with the mutex held
+func (t *Thread) queueStateChangeLocked(old, new State) {
+	t.stateChanges = append(t.stateChanges, stateChange{old: old, new: new})
+	if !t.dispatching {
+		t.dispatching = true
+		go t.dispatchStateChanges(t.onStateChange)
+	}
+}
+
+// Internal helper delivering queued state changes until the queue is empty
+func (t *Thread) dispatchStateChanges(hook func(old, new State)) {
+	for {
+		t.mutex.Lock()
+		changes := t.stateChanges
+		t.stateChanges = nil
+		if len(changes) == 0 {
+			t.dispatching = false
+			t.mutex.Unlock()
+			return
+		}
+		t.mutex.Unlock()
+		for _, change := range changes {
+			hook(change.old, change.new)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBufferDropNewest(t *testing.T) {
+	thread := New(&blockingRunnable{}, WithEventBuffer(2, DropNewest))
+	events := thread.Events()
+	for i := 0; i < 3; i++ {
+		thread.Start()
+		thread.Stop()
+		if !thread.WaitState(STOPPED, time.Second) {
+			t.Fatal("expected thread to stop despite a non-reading subscriber")
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected full buffer of 2, got %d", len(events))
+	}
+	for _, state := range []State{RUNNING, STOPPING} {
+		if event := <-events; event.State != state {
+			t.Fatalf("expected oldest events to be kept, got state %d instead of %d", event.State, state)
+		}
+	}
+}
+
+func TestEventBufferDropOldest(t *testing.T) {
+	thread := New(&blockingRunnable{}, WithEventBuffer(2, DropOldest))
+	events := thread.Events()
+	for i := 0; i < 3; i++ {
+		thread.Start()
+		thread.Stop()
+		if !thread.WaitState(STOPPED, time.Second) {
+			t.Fatal("expected thread to stop despite a non-reading subscriber")
+		}
+	}
+	for _, state := range []State{STOPPING, STOPPED} {
+		if event := <-events; event.State != state {
+			t.Fatalf("expected newest events to be kept, got state %d instead of %d", event.State, state)
+		}
+	}
+}
+
+func TestDroppedEvents(t *testing.T) {
+	thread := New(&blockingRunnable{}, WithEventBuffer(1, DropNewest))
+	thread.Events()
+	thread.Start()
+	thread.Stop()
+	thread.Join()
+	// RUNNING fits into the buffer, STOPPING and STOPPED are dropped
+	if dropped := thread.DroppedEvents(); dropped != 2 {
+		t.Fatalf("expected 2 dropped events, got %d", dropped)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	changes := make(chan stateChange, 8)
+	var thread *Thread
+	thread = New(&blockingRunnable{}, WithOnStateChange(func(old, new State) {
+		// must not deadlock, the hook is called outside of the mutex
+		thread.State()
+		changes <- stateChange{old: old, new: new}
+	}))
+	thread.Start()
+	thread.Stop()
+	thread.Join()
+	expected := []stateChange{{STOPPED, RUNNING}, {RUNNING, STOPPING}, {STOPPING, STOPPED}}
+	for _, want := range expected {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("expected transition %s -> %s, got %s -> %s", want.old, want.new, got.old, got.new)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected transition %s -> %s to be reported", want.old, want.new)
+		}
+	}
+}
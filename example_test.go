@@ -1,6 +1,7 @@
 package thread
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -12,14 +13,12 @@ type ExampleRunnable struct {
 func (t *ExampleRunnable) Run(stop chan bool) error {
 	fmt.Println("ExampleRunnable.Run()")
 	for {
-		select {
-		case <-stop:
+		fmt.Printf("ExampleRunnable.Counter = %d\n", t.Counter)
+		t.Counter++
+		// wait for the next iteration, but return as soon as stop is closed
+		if !Sleep(stop, 400*time.Millisecond) {
 			fmt.Println(" <-stop")
 			return nil
-		default:
-			fmt.Printf("ExampleRunnable.Counter = %d\n", t.Counter)
-			t.Counter++
-			time.Sleep(400 * time.Millisecond)
 		}
 	}
 }
@@ -71,3 +70,21 @@ func ExampleThread() {
 	// Exit
 
 }
+
+func ExampleFromFunc() {
+	// an existing function observing its context
+	serve := func(ctx context.Context) error {
+		fmt.Println("serving")
+		<-ctx.Done()
+		fmt.Println("cancelled:", context.Cause(ctx))
+		return nil
+	}
+	thread := New(FromFunc(serve)).Start()
+	thread.Await(time.Second)
+	thread.Stop()
+	thread.Join()
+
+	// Output:
+	// serving
+	// cancelled: Thread has been stopped
+}
@@ -0,0 +1,3 @@
+module github.com/ms-xy/thread
+
+go 1.21
@@ -0,0 +1,447 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Group manages a set of Threads as a single unit.
+type Group struct {
+	mutex      sync.Mutex
+	threads    []*Thread
+	priorities map[*Thread]int
+	scaling    sync.Mutex
+	release    func() bool
+	ctx        context.Context
+	cancel     context.CancelCauseFunc
+}
+
+// NewGroup creates a new, empty Group.
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// NewGroupWithContext creates a new, empty Group which stops all of its members
+// via StopAll() once ctx is done. Call Close() to release the Group from ctx.
+func NewGroupWithContext(ctx context.Context) *Group {
+	g := NewGroup()
+	g.release = context.AfterFunc(ctx, g.StopAll)
+	return g
+}
+
+// Context returns the context shared by all members, which is cancelled with
+// ErrStopped once StopAll() is called, e.g. as a common "shutting down" signal
+// in member logic. Members created by the Group, via StartGroup, Spawn or
+// Scale, derive the context of their runs from it, others may be created via
+// NewWithContext(g.Context(), ...). StopAll replaces the cancelled context with
+// a new one for the runs started afterwards, so call Context again after a
+// restart of the Group.
+func (g *Group) Context() context.Context {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.contextLocked()
+}
+
+// Internal helper returning the shared context, creating it on first use, must
+// be called with the mutex held
+func (g *Group) contextLocked() context.Context {
+	if g.ctx == nil {
+		g.ctx, g.cancel = context.WithCancelCause(context.Background())
+	}
+	return g.ctx
+}
+
+// Close stops all members and, for Groups created via NewGroupWithContext(),
+// stops watching the context. Use JoinAll to wait for the members.
+func (g *Group) Close() {
+	g.mutex.Lock()
+	release := g.release
+	g.release = nil
+	g.mutex.Unlock()
+	if release != nil {
+		release()
+	}
+	g.StopAll()
+}
+
+// StartGroup creates a Thread per Runnable, adds them to a new Group and starts
+// all of them.
+func StartGroup(runnables ...Runnable) *Group {
+	g := NewGroup()
+	for _, runnable := range runnables {
+		g.Add(NewWithContext(g.Context(), runnable))
+	}
+	g.StartAll()
+	return g
+}
+
+// Add adds the given Threads to the Group. They are not started implicitly.
+func (g *Group) Add(threads ...*Thread) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.threads = append(g.threads, threads...)
+}
+
+// AddWithPriority adds the given Thread to the Group with a start priority.
+// StartAll starts members in ascending priority order. Members added via Add
+// have priority 0.
+func (g *Group) AddWithPriority(t *Thread, priority int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.priorities == nil {
+		g.priorities = make(map[*Thread]int)
+	}
+	g.threads = append(g.threads, t)
+	g.priorities[t] = priority
+}
+
+// Spawn creates a Thread for the given Runnable, adds it to the Group and
+// starts it.
+func (g *Group) Spawn(runnable Runnable) *Thread {
+	t := NewWithContext(g.Context(), runnable)
+	g.Add(t)
+	t.Start()
+	return t
+}
+
+// Scale adjusts the Group to exactly n running members. Stopped members, e.g.
+// crashed ones or those never started, are removed and do not count. Missing
+// members are created via factory, passing the index the new member gets, and
+// started. Excess members are removed, most recently added first, see
+// Remove(). Concurrent calls to Scale are serialized.
+func (g *Group) Scale(n int, factory func(i int) Runnable) {
+	g.scaling.Lock()
+	defer g.scaling.Unlock()
+	var running []*Thread
+	for _, t := range g.members() {
+		if t.State() == STOPPED {
+			g.Remove(t)
+		} else {
+			running = append(running, t)
+		}
+	}
+	for i := len(running); i < n; i++ {
+		g.Spawn(factory(i))
+	}
+	for i := len(running) - 1; i >= n && i >= 0; i-- {
+		g.Remove(running[i])
+	}
+}
+
+// Remove stops the given member, waits for it to terminate and removes it from
+// the Group. It returns false if the Thread is not a member of the Group.
+func (g *Group) Remove(t *Thread) bool {
+	g.mutex.Lock()
+	found := false
+	for i, member := range g.threads {
+		if member == t {
+			g.threads = append(g.threads[:i:i], g.threads[i+1:]...)
+			delete(g.priorities, t)
+			found = true
+			break
+		}
+	}
+	g.mutex.Unlock()
+	if !found {
+		return false
+	}
+	t.stop(false)
+	awaitTermination(t)
+	return true
+}
+
+// Replace stops the member old and replaces it in place with a new Thread for
+// the given Runnable, which inherits the configuration of old, such as its
+// options and priority. Once old terminated, the new Thread is started and
+// returned. It returns ErrNotMember if old is not a member of the Group.
+func (g *Group) Replace(old *Thread, runnable Runnable) (*Thread, error) {
+	if runnable == nil {
+		return nil, ErrNilRunnable
+	}
+	replacement := old.derive(runnable)
+	g.mutex.Lock()
+	found := false
+	for i, member := range g.threads {
+		if member == old {
+			g.threads[i] = replacement
+			found = true
+			break
+		}
+	}
+	if found {
+		if priority, ok := g.priorities[old]; ok {
+			delete(g.priorities, old)
+			g.priorities[replacement] = priority
+		}
+	}
+	g.mutex.Unlock()
+	if !found {
+		return nil, ErrNotMember
+	}
+	old.stop(false)
+	awaitTermination(old)
+	return replacement.Start(), nil
+}
+
+// Internal helper waiting for the termination of the given Thread, members
+// which have never been started have nothing to wait for
+func awaitTermination(t *Thread) {
+	t.mutex.Lock()
+	done := t.waitThread
+	t.mutex.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Internal helper returning a copy of the member list
+func (g *Group) members() []*Thread {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return append([]*Thread(nil), g.threads...)
+}
+
+// StartAll starts all member Threads in ascending priority order. Members of
+// the same priority are started together and the next priority is only started
+// once all of them are ready (or stopped). A member restarted automatically
+// before signaling readiness holds up the next priority until its restarted run
+// is ready.
+func (g *Group) StartAll() {
+	g.mutex.Lock()
+	threads := append([]*Thread(nil), g.threads...)
+	priorities := make(map[*Thread]int, len(g.priorities))
+	for t, priority := range g.priorities {
+		priorities[t] = priority
+	}
+	g.mutex.Unlock()
+	sort.SliceStable(threads, func(i, j int) bool {
+		return priorities[threads[i]] < priorities[threads[j]]
+	})
+	for i := 0; i < len(threads); {
+		// start the whole tier, then wait for it to become ready
+		j := i
+		for j < len(threads) && priorities[threads[j]] == priorities[threads[i]] {
+			threads[j].start(false)
+			j++
+		}
+		if j < len(threads) {
+			for _, t := range threads[i:j] {
+				t.awaitReady(nil)
+			}
+		}
+		i = j
+	}
+}
+
+// Internal helper cancelling the shared context and moving the members derived
+// from it over to a new one, must be called with the mutex held
+func (g *Group) renewContextLocked() {
+	if g.ctx == nil {
+		return
+	}
+	old := g.ctx
+	g.cancel(ErrStopped)
+	g.ctx = nil
+	ctx := g.contextLocked()
+	for _, t := range g.threads {
+		t.mutex.Lock()
+		if t.ctx == old {
+			t.ctx = ctx
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// StopAll cancels the shared context and signals all member Threads to stop,
+// use JoinAll to wait for them.
+func (g *Group) StopAll() {
+	g.mutex.Lock()
+	g.renewContextLocked()
+	g.mutex.Unlock()
+	for _, t := range g.members() {
+		t.stop(false)
+	}
+}
+
+// JoinAll blocks until all member Threads terminated and returns their errors
+// joined together, or nil if none of them failed. Members which have never
+// been started are skipped.
+func (g *Group) JoinAll() error {
+	var errs []error
+	for _, t := range g.members() {
+		if t.Done() == nil {
+			continue
+		}
+		if err := t.Join(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RollingRestart restarts the running members one at a time, waiting delay
+// after each restarted member is running again before moving on to the next.
+// This way at most one member is down at any time. Members which are not
+// running are skipped.
+func (g *Group) RollingRestart(delay time.Duration) {
+	for _, t := range g.members() {
+		if t.State() != RUNNING {
+			continue
+		}
+		t.stop(false)
+		// Join returns early once a stop timeout expired, the member must be
+		// stopped for the start to take effect
+		awaitTermination(t)
+		t.start(false)
+		time.Sleep(delay)
+	}
+}
+
+// WaitRunning blocks until at least count members are RUNNING or the timeout
+// elapses and reports whether enough members were running. Members added while
+// waiting are taken into account on the next state transition of any member.
+func (g *Group) WaitRunning(count int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// watch all members, the timer being the first case
+		cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)}}
+		running := 0
+		for _, t := range g.members() {
+			state, changed := t.watchState()
+			if state == RUNNING {
+				running++
+			}
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(changed)})
+		}
+		if running >= count {
+			return true
+		}
+		// wait for the next transition of any member, then count again
+		if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+			return false
+		}
+	}
+}
+
+// JoinFirst blocks until any started member terminated and returns it along
+// with the result of its Join(). The other members keep running. Members which
+// already terminated count as well, members which have never been started are
+// ignored. It returns ErrNotStarted if no member has been started.
+func (g *Group) JoinFirst() (*Thread, error) {
+	var threads []*Thread
+	var cases []reflect.SelectCase
+	for _, t := range g.members() {
+		if done := t.Done(); done != nil {
+			threads = append(threads, t)
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+		}
+	}
+	if len(cases) == 0 {
+		return nil, ErrNotStarted
+	}
+	chosen, _, _ := reflect.Select(cases)
+	return threads[chosen], threads[chosen].Join()
+}
+
+// Healthy reports whether all members of the Group are healthy.
+func (g *Group) Healthy() bool {
+	return len(g.UnhealthyThreads()) == 0
+}
+
+// UnhealthyThreads returns all members which are not healthy.
+func (g *Group) UnhealthyThreads() []*Thread {
+	return g.Filter(func(t *Thread) bool {
+		return !t.Healthy()
+	})
+}
+
+// Filter returns the members for which pred returns true in member order, e.g.
+// to stop just a subset of the Group. pred is called outside of the Group's
+// mutex, so it may use other methods of the Group.
+func (g *Group) Filter(pred func(*Thread) bool) []*Thread {
+	var matching []*Thread
+	for _, t := range g.members() {
+		if pred(t) {
+			matching = append(matching, t)
+		}
+	}
+	return matching
+}
+
+// Map calls fn for every member concurrently and returns the errors in member
+// order, with nil entries for members for which fn succeeded.
+func (g *Group) Map(fn func(*Thread) error) []error {
+	threads := g.members()
+	errs := make([]error, len(threads))
+	var wg sync.WaitGroup
+	for i, t := range threads {
+		wg.Add(1)
+		go func(i int, t *Thread) {
+			defer wg.Done()
+			errs[i] = fn(t)
+		}(i, t)
+	}
+	wg.Wait()
+	return errs
+}
+
+// GroupReport summarizes the shutdown of a Group, see ShutdownReport.
+type GroupReport struct {
+	Members []MemberReport // in member order
+}
+
+// MemberReport describes the shutdown of a single member of a Group.
+type MemberReport struct {
+	Thread       *Thread
+	Name         string
+	Stopped      bool          // whether the member terminated
+	StopDuration time.Duration // from the stop request until termination, or until now while stopping
+	TimedOut     bool          // whether stopping exceeded the stop timeout of the member
+	Err          error         // the error of the member's most recent run, as reported by LastError()
+}
+
+// ShutdownReport summarizes how the members shut down, e.g. after StopAll()
+// and JoinAll(), for diagnosing slow shutdowns: how long each member took to
+// stop, which exceeded its stop timeout, see SetStopTimeout(), and which
+// failed. Members which have not been stopped via Stop, e.g. because they
+// returned on their own, report a zero StopDuration.
+func (g *Group) ShutdownReport() GroupReport {
+	threads := g.members()
+	report := GroupReport{Members: make([]MemberReport, len(threads))}
+	for i, t := range threads {
+		report.Members[i] = t.shutdownReport()
+	}
+	return report
+}
+
+// TimedOut returns the members which exceeded their stop timeout.
+func (r GroupReport) TimedOut() []*Thread {
+	var threads []*Thread
+	for _, member := range r.Members {
+		if member.TimedOut {
+			threads = append(threads, member.Thread)
+		}
+	}
+	return threads
+}
+
+// Internal helper describing the shutdown of the Thread
+func (t *Thread) shutdownReport() MemberReport {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	report := MemberReport{Thread: t, Name: t.name, Stopped: t.state == STOPPED, Err: t.err}
+	if !t.stopRequested.IsZero() {
+		end := time.Now()
+		if report.Stopped {
+			end = t.stateChangedAt
+		}
+		report.StopDuration = end.Sub(t.stopRequested)
+		report.TimedOut = t.stopTimeout > 0 && report.StopDuration > t.stopTimeout
+	}
+	return report
+}
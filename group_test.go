@@ -0,0 +1,577 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartGroup(t *testing.T) {
+	g := StartGroup(&blockingRunnable{}, &blockingRunnable{}, &blockingRunnable{})
+	members := g.members()
+	if len(members) != 3 {
+		t.Fatalf("expected 3 members, got %d", len(members))
+	}
+	for i, member := range members {
+		if !member.WaitState(RUNNING, time.Second) {
+			t.Fatalf("expected member %d to be running", i)
+		}
+	}
+
+	g.StopAll()
+	if err := g.JoinAll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, member := range members {
+		if state := member.State(); state != STOPPED {
+			t.Fatalf("expected member %d to be stopped, got state %d", i, state)
+		}
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	const size = 4
+	g := NewGroup()
+	for i := 0; i < size; i++ {
+		g.Add(New(&blockingRunnable{}))
+	}
+
+	// track the number of running members through their events
+	var running, minRunning int32 = 0, size
+	var wg sync.WaitGroup
+	starts := make(chan struct{}, size*2)
+	done := make(chan struct{})
+	for _, member := range g.members() {
+		wg.Add(1)
+		go func(events <-chan Event) {
+			defer wg.Done()
+			for {
+				select {
+				case event := <-events:
+					switch event.State {
+					case RUNNING:
+						atomic.AddInt32(&running, 1)
+						starts <- struct{}{}
+					case STOPPING:
+						if n := atomic.AddInt32(&running, -1); n < atomic.LoadInt32(&minRunning) {
+							atomic.StoreInt32(&minRunning, n)
+						}
+					}
+				case <-done:
+					return
+				}
+			}
+		}(member.Events())
+	}
+	g.StartAll()
+	for i := 0; i < size; i++ {
+		<-starts
+	}
+
+	g.RollingRestart(20 * time.Millisecond)
+	for i := 0; i < size; i++ {
+		<-starts
+	}
+	close(done)
+	wg.Wait()
+	if n := atomic.LoadInt32(&minRunning); n < size-1 {
+		t.Fatalf("expected at least %d running members throughout, got %d", size-1, n)
+	}
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupHealthy(t *testing.T) {
+	sick := &healthRunnable{}
+	g := StartGroup(&blockingRunnable{}, sick, &blockingRunnable{})
+	defer func() {
+		g.StopAll()
+		g.JoinAll()
+	}()
+
+	if g.Healthy() {
+		t.Fatal("expected group with an unhealthy member to be unhealthy")
+	}
+	unhealthy := g.UnhealthyThreads()
+	if len(unhealthy) != 1 || unhealthy[0] != g.members()[1] {
+		t.Fatalf("expected exactly the second member to be unhealthy, got %v", unhealthy)
+	}
+
+	sick.healthy.Store(true)
+	if !g.Healthy() {
+		t.Fatal("expected group to be healthy once all members are")
+	}
+}
+
+// startRecorder records the order in which runnables started
+type startRecorder struct {
+	readyRunnable
+	id      int
+	started chan int
+}
+
+func (r *startRecorder) RunReady(stop chan bool, ready func()) error {
+	r.started <- r.id
+	return r.readyRunnable.RunReady(stop, ready)
+}
+
+func TestStartAllPriority(t *testing.T) {
+	started := make(chan int, 3)
+	g := NewGroup()
+	g.AddWithPriority(New(&startRecorder{id: 2, started: started}), 2)
+	g.Add(New(&startRecorder{id: 0, started: started, readyRunnable: readyRunnable{delay: 20 * time.Millisecond}}))
+	g.AddWithPriority(New(&startRecorder{id: 1, started: started, readyRunnable: readyRunnable{delay: 20 * time.Millisecond}}), 1)
+	g.StartAll()
+	defer func() {
+		g.StopAll()
+		g.JoinAll()
+	}()
+
+	for expected := 0; expected < 3; expected++ {
+		if id := <-started; id != expected {
+			t.Fatalf("expected runnable %d to start next, got %d", expected, id)
+		}
+	}
+}
+
+func TestStartAllRestartBeforeReady(t *testing.T) {
+	g := NewGroup()
+	first := New(&failReadyRunnable{}, WithAutoRestart(-1, 10*time.Millisecond))
+	second := New(&blockingRunnable{})
+	g.AddWithPriority(first, 0)
+	g.AddWithPriority(second, 1)
+	done := make(chan struct{})
+	go func() {
+		g.StartAll()
+		close(done)
+	}()
+	defer func() {
+		g.StopAll()
+		g.JoinAll()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected StartAll to continue once the restarted member is ready")
+	}
+	if second.State() != RUNNING {
+		t.Fatal("expected the next tier to be started")
+	}
+}
+
+func TestGroupMap(t *testing.T) {
+	g := NewGroup()
+	g.Add(New(&blockingRunnable{}), New(&blockingRunnable{}), New(&blockingRunnable{}))
+	running := g.members()[1]
+	running.Start()
+	defer func() {
+		running.Stop()
+		running.Join()
+	}()
+
+	errNotRunning := errors.New("not running")
+	var calls atomic.Int32
+	errs := g.Map(func(member *Thread) error {
+		calls.Add(1)
+		if member.State() != RUNNING {
+			return errNotRunning
+		}
+		return nil
+	})
+	if calls.Load() != 3 {
+		t.Fatalf("expected fn to be called for all 3 members, got %d calls", calls.Load())
+	}
+	if len(errs) != 3 || errs[0] != errNotRunning || errs[1] != nil || errs[2] != errNotRunning {
+		t.Fatalf("expected errors for the stopped members only, got %v", errs)
+	}
+}
+
+func TestGroupRemove(t *testing.T) {
+	g := StartGroup(&blockingRunnable{}, &blockingRunnable{}, &blockingRunnable{})
+	members := g.members()
+	for i, member := range members {
+		if !member.WaitState(RUNNING, time.Second) {
+			t.Fatalf("expected member %d to be running", i)
+		}
+	}
+
+	if !g.Remove(members[1]) {
+		t.Fatal("expected member to be removed")
+	}
+	if state := members[1].State(); state != STOPPED {
+		t.Fatalf("expected removed member to be stopped, got state %d", state)
+	}
+	if g.Remove(members[1]) {
+		t.Fatal("expected second removal to report a missing member")
+	}
+	remaining := g.members()
+	if len(remaining) != 2 || remaining[0] != members[0] || remaining[1] != members[2] {
+		t.Fatalf("expected the other members to remain, got %v", remaining)
+	}
+	for i, member := range remaining {
+		if state := member.State(); state != RUNNING {
+			t.Fatalf("expected member %d to keep running, got state %d", i, state)
+		}
+	}
+
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupScaleReplacesStopped(t *testing.T) {
+	g := NewGroup()
+	var created []int
+	factory := func(i int) Runnable {
+		created = append(created, i)
+		return &blockingRunnable{}
+	}
+	g.Scale(3, factory)
+	initial := g.members()
+	// a crashed member and one which was only added do not count
+	initial[1].StopAndJoin()
+	g.Add(New(&blockingRunnable{}))
+	g.Scale(3, factory)
+	defer func() {
+		g.StopAll()
+		g.JoinAll()
+	}()
+	members := g.members()
+	if len(members) != 3 || members[0] != initial[0] || members[1] != initial[2] {
+		t.Fatalf("expected the running members to be kept and the others removed, got %d members", len(members))
+	}
+	if len(created) != 4 || created[3] != 2 {
+		t.Fatalf("expected one replacement at index 2, got %v", created)
+	}
+	for i, member := range members {
+		if !member.WaitState(RUNNING, time.Second) {
+			t.Fatalf("expected member %d to be running", i)
+		}
+	}
+}
+
+func TestGroupScale(t *testing.T) {
+	g := NewGroup()
+	var created []int
+	factory := func(i int) Runnable {
+		created = append(created, i)
+		return &blockingRunnable{}
+	}
+
+	g.Scale(2, factory)
+	initial := g.members()
+	g.Scale(5, factory)
+	members := g.members()
+	if len(members) != 5 || members[0] != initial[0] || members[1] != initial[1] {
+		t.Fatalf("expected 5 members keeping the initial ones, got %d", len(members))
+	}
+	if len(created) != 5 || created[2] != 2 || created[4] != 4 {
+		t.Fatalf("expected factory to be called with indices 0 to 4, got %v", created)
+	}
+	for i, member := range members {
+		if !member.WaitState(RUNNING, time.Second) {
+			t.Fatalf("expected member %d to be running", i)
+		}
+	}
+
+	g.Scale(1, factory)
+	remaining := g.members()
+	if len(remaining) != 1 || remaining[0] != members[0] {
+		t.Fatalf("expected the first member to remain, got %d members", len(remaining))
+	}
+	if state := remaining[0].State(); state != RUNNING {
+		t.Fatalf("expected remaining member to keep running, got state %d", state)
+	}
+	for i, member := range members[1:] {
+		if state := member.State(); state != STOPPED {
+			t.Fatalf("expected member %d to be stopped, got state %d", i+1, state)
+		}
+	}
+
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewGroupWithContext(ctx)
+	defer g.Close()
+	g.Spawn(&blockingRunnable{})
+	g.Spawn(&blockingRunnable{})
+
+	cancel()
+	if err := g.JoinAll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, member := range g.members() {
+		if state := member.State(); state != STOPPED {
+			t.Fatalf("expected member %d to be stopped, got state %d", i, state)
+		}
+	}
+}
+
+func TestGroupClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := NewGroupWithContext(ctx)
+	member := g.Spawn(&blockingRunnable{})
+
+	g.Close()
+	member.Join()
+	// a closed group no longer reacts to the context
+	member.Start()
+	cancel()
+	if member.WaitState(STOPPING, 50*time.Millisecond) {
+		t.Fatal("expected member to keep running after close")
+	}
+	member.Stop()
+	member.Join()
+}
+
+func TestGroupWaitRunning(t *testing.T) {
+	g := NewGroup()
+	for i := 0; i < 3; i++ {
+		g.Add(New(&blockingRunnable{}))
+	}
+	if g.WaitRunning(1, 20*time.Millisecond) {
+		t.Fatal("expected no member to be running")
+	}
+
+	// start the members staggered
+	go func() {
+		for _, member := range g.members() {
+			time.Sleep(10 * time.Millisecond)
+			member.Start()
+		}
+	}()
+	if !g.WaitRunning(2, time.Second) {
+		t.Fatal("expected a quorum of 2 running members")
+	}
+	if !g.WaitRunning(3, time.Second) {
+		t.Fatal("expected all members to be running")
+	}
+
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupReplace(t *testing.T) {
+	g := NewGroup()
+	first := New(&blockingRunnable{})
+	old := New(&blockingRunnable{}, WithName("worker"))
+	g.Add(first)
+	g.AddWithPriority(old, 2)
+	g.StartAll()
+
+	started := make(chan struct{})
+	replacement, err := g.Replace(old, &signalRunnable{started: started})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := old.State(); state != STOPPED {
+		t.Fatalf("expected old member to be stopped, got state %d", state)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected the new runnable to run")
+	}
+	members := g.members()
+	if len(members) != 2 || members[0] != first || members[1] != replacement {
+		t.Fatalf("expected the member to be replaced in place, got %v", members)
+	}
+	if replacement.Name() != "worker" || g.priorities[replacement] != 2 {
+		t.Fatal("expected name and priority to be inherited")
+	}
+	if _, err := g.Replace(old, &blockingRunnable{}); err != ErrNotMember {
+		t.Fatalf("expected ErrNotMember, got %v", err)
+	}
+
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupJoinFirst(t *testing.T) {
+	g := NewGroup()
+	if _, err := g.JoinFirst(); err != ErrNotStarted {
+		t.Fatalf("expected ErrNotStarted for an empty group, got %v", err)
+	}
+	release := make(chan struct{})
+	g.Spawn(&blockingRunnable{})
+	early := g.Spawn(&releaseRunnable{release: release, err: errTemporary})
+	g.Spawn(&blockingRunnable{})
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	first, err := g.JoinFirst()
+	if first != early || !errors.Is(err, errTemporary) {
+		t.Fatalf("expected the early member and its error, got %v", err)
+	}
+	for _, member := range g.members() {
+		if member != early && member.State() != RUNNING {
+			t.Fatal("expected the other members to keep running")
+		}
+	}
+
+	g.StopAll()
+	g.JoinAll()
+}
+
+func TestGroupContext(t *testing.T) {
+	g := NewGroup()
+	ctx := g.Context()
+	causes := make(chan error, 2)
+	observe := FromFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+	g.Spawn(observe)
+	// a member created elsewhere observing the shared context in its logic
+	shared := New(FromFunc(func(context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	}))
+	g.Add(shared)
+	shared.Start()
+	if !g.WaitRunning(2, time.Second) {
+		t.Fatal("expected members to be running")
+	}
+	g.StopAll()
+	for i := 0; i < 2; i++ {
+		if cause := <-causes; cause != ErrStopped {
+			t.Fatalf("expected members to observe ErrStopped, got %v", cause)
+		}
+	}
+	if err := g.JoinAll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// spawned members start over with a fresh context
+	if g.Context().Err() != nil {
+		t.Fatal("expected StopAll to renew the shared context")
+	}
+	g.Remove(shared)
+	g.StartAll()
+	select {
+	case cause := <-causes:
+		t.Fatalf("expected restarted member to keep running, observed %v", cause)
+	case <-time.After(20 * time.Millisecond):
+	}
+	g.StopAll()
+	g.JoinAll()
+	<-causes
+}
+
+func TestJoinAllNeverStarted(t *testing.T) {
+	g := NewGroup()
+	g.Spawn(&returnRunnable{})
+	g.Add(New(&blockingRunnable{}))
+	done := make(chan error)
+	go func() {
+		done <- g.JoinAll()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected JoinAll to skip the member never started")
+	}
+}
+
+func TestGroupFilter(t *testing.T) {
+	g := NewGroup()
+	running := []*Thread{g.Spawn(&blockingRunnable{}), g.Spawn(&blockingRunnable{})}
+	stopped := New(&blockingRunnable{})
+	g.Add(stopped)
+	g.Spawn(&blockingRunnable{}).StopAndJoin()
+	defer func() {
+		g.StopAll()
+		g.JoinAll()
+	}()
+	if !g.WaitRunning(2, time.Second) {
+		t.Fatal("expected members to be running")
+	}
+	matching := g.Filter(func(t *Thread) bool {
+		return t.State() == RUNNING
+	})
+	if len(matching) != len(running) {
+		t.Fatalf("expected %d running members, got %d", len(running), len(matching))
+	}
+	for i, member := range matching {
+		if member != running[i] {
+			t.Fatalf("expected running member %d in member order", i)
+		}
+	}
+	if matching := g.Filter(func(*Thread) bool { return false }); matching != nil {
+		t.Fatalf("expected no members, got %d", len(matching))
+	}
+}
+
+// slowStopRunnable takes a while to shut down once stopped
+type slowStopRunnable struct {
+	delay time.Duration
+	err   error
+}
+
+func (r *slowStopRunnable) Run(stop chan bool) error {
+	<-stop
+	time.Sleep(r.delay)
+	return r.err
+}
+
+func TestShutdownReport(t *testing.T) {
+	g := NewGroup()
+	fast := New(&blockingRunnable{}, WithName("fast"), WithStopTimeout(time.Second))
+	slow := New(&slowStopRunnable{delay: 50 * time.Millisecond}, WithName("slow"), WithStopTimeout(20*time.Millisecond))
+	failing := New(&slowStopRunnable{err: errTemporary}, WithName("failing"))
+	g.Add(fast, slow, failing)
+	g.StartAll()
+	if !g.WaitRunning(3, time.Second) {
+		t.Fatal("expected members to be running")
+	}
+	g.StopAll()
+	if err := g.JoinAll(); err == nil {
+		t.Fatal("expected errors of the slow and the failing member")
+	}
+	// the slow member is still stopping
+	report := g.ShutdownReport()
+	if len(report.Members) != 3 {
+		t.Fatalf("expected 3 members, got %d", len(report.Members))
+	}
+	if timedOut := report.TimedOut(); len(timedOut) != 1 || timedOut[0] != slow {
+		t.Fatalf("expected only the slow member to be flagged, got %v", timedOut)
+	}
+	if member := report.Members[1]; member.Name != "slow" || member.Stopped || member.StopDuration < 20*time.Millisecond {
+		t.Fatalf("expected the slow member to be stopping for a while, got %+v", member)
+	}
+	if member := report.Members[0]; !member.Stopped || member.TimedOut || member.Err != nil {
+		t.Fatalf("expected the fast member to stop cleanly, got %+v", member)
+	}
+	if member := report.Members[2]; !member.Stopped || !errors.Is(member.Err, errTemporary) {
+		t.Fatalf("expected the failing member to report its error, got %+v", member)
+	}
+	awaitTermination(slow)
+	if member := g.ShutdownReport().Members[1]; !member.Stopped || !member.TimedOut || member.StopDuration < 50*time.Millisecond {
+		t.Fatalf("expected the slow member to have stopped late, got %+v", member)
+	}
+}
+
+func TestRollingRestartStopTimeout(t *testing.T) {
+	g := NewGroup()
+	member := New(&slowStopRunnable{delay: 50 * time.Millisecond}, WithStopTimeout(5*time.Millisecond))
+	g.Add(member)
+	g.StartAll()
+	g.RollingRestart(0)
+	if n := member.StartCount(); n != 2 {
+		t.Fatalf("expected the member to be restarted despite its stop timeout, got %d starts", n)
+	}
+	if state := member.State(); state != RUNNING {
+		t.Fatalf("expected the member to be running again, got %s", state)
+	}
+	g.StopAll()
+	awaitTermination(member)
+}
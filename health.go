@@ -0,0 +1,22 @@
+package thread
+
+// HealthChecker may be implemented by a Runnable to report its own health.
+type HealthChecker interface {
+	Healthy() bool
+}
+
+// Healthy reports whether the Thread is running and, if its Runnable
+// implements HealthChecker, whether the Runnable considers itself healthy.
+func (t *Thread) Healthy() bool {
+	t.mutex.Lock()
+	state, runnable := t.state, t.runnable
+	t.mutex.Unlock()
+	if state != RUNNING {
+		return false
+	}
+	// ask the runnable outside of the mutex, it may take a while
+	if checker, ok := runnable.(HealthChecker); ok {
+		return checker.Healthy()
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package thread
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// healthRunnable runs until stopped and reports the configured health
+type healthRunnable struct {
+	blockingRunnable
+	healthy atomic.Bool
+}
+
+func (r *healthRunnable) Healthy() bool {
+	return r.healthy.Load()
+}
+
+func TestHealthy(t *testing.T) {
+	runnable := &healthRunnable{}
+	runnable.healthy.Store(true)
+	thread := New(runnable)
+	if thread.Healthy() {
+		t.Fatal("expected stopped thread to be unhealthy")
+	}
+	thread.Start()
+	if !thread.Healthy() {
+		t.Fatal("expected running thread with healthy runnable to be healthy")
+	}
+	runnable.healthy.Store(false)
+	if thread.Healthy() {
+		t.Fatal("expected thread with unhealthy runnable to be unhealthy")
+	}
+	thread.Stop()
+	thread.Join()
+}
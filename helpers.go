@@ -0,0 +1,152 @@
+package thread
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+// Sleep pauses the calling Runnable for the given duration, but wakes up early
+// if stop is closed. It returns true if it slept the full duration and false
+// if it was interrupted by the stop signal.
+func Sleep(stop chan bool, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// StartN creates and starts n independent Threads, each running the Runnable
+// returned by factory for its index, and returns them in index order. Use Join
+// on each of them to wait for their termination, or Group for managing them as
+// a single unit.
+func StartN(n int, factory func(i int) Runnable) []*Thread {
+	threads := make([]*Thread, n)
+	for i := range threads {
+		threads[i] = New(factory(i))
+		threads[i].Start()
+	}
+	return threads
+}
+
+// Clock hook used by RestartIfOlderThan, replaced in tests
+var clockNow = time.Now
+
+// RestartIfOlderThan restarts the Thread if it has been running for longer than
+// d since its last Start(), and reports whether it did so. A scheduler may call
+// it periodically to recycle long-lived workers, e.g. to drop leaked memory.
+// The restart stops the Thread, waits for it to terminate, even beyond its stop
+// timeout, and starts it again, so a waiting Join() returns in between. Threads
+// which are not running are left alone. It returns false if another caller
+// started the Thread in the meantime.
+func (t *Thread) RestartIfOlderThan(d time.Duration) bool {
+	// check the age and stop in one critical section, so a concurrent restart
+	// is never stopped by mistake
+	t.mutex.Lock()
+	done := t.waitThread
+	if t.state != RUNNING || clockNow().Sub(t.started) <= d {
+		t.mutex.Unlock()
+		return false
+	}
+	t.requestStopLocked()
+	t.mutex.Unlock()
+	<-done
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.waitThread != done || t.state != STOPPED {
+		return false
+	}
+	go t.run(t.startLocked())
+	return true
+}
+
+// Scheduler hook used by Yield, replaced in tests
+var gosched = runtime.Gosched
+
+// Yield is a best-effort priority hint for CPU-bound Runnables, as Go has no
+// goroutine priorities: called once per iteration, it yields to other
+// goroutines via runtime.Gosched every budget iterations, approximating a low
+// priority worker. counter tracks the iterations and must not be shared
+// between goroutines. It reports whether it yielded. A budget of zero or less
+// never yields. See WithYieldBudget for configuring the budget per Thread.
+func Yield(counter *int, budget int) bool {
+	if budget <= 0 {
+		return false
+	}
+	*counter++
+	if *counter < budget {
+		return false
+	}
+	*counter = 0
+	gosched()
+	return true
+}
+
+// WithYieldBudget sets the number of iterations after which the Runnable should
+// yield the CPU, see Yield and YieldBudget.
+func WithYieldBudget(n int) Option {
+	return func(t *Thread) {
+		t.yieldBudget = n
+	}
+}
+
+// YieldBudget returns the budget set via WithYieldBudget for use with Yield by
+// the Runnable, e.g. Yield(&i, thread.YieldBudget()), or zero if none is set.
+func (t *Thread) YieldBudget() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.yieldBudget
+}
+
+// StopChain stops the given Threads one by one in the given order, waiting for
+// each to terminate before stopping the next, e.g. for the ordered teardown of
+// a pipeline without a Group. It returns the errors of all Threads joined
+// together, or nil if none of them failed. Threads which have never been
+// started are skipped.
+func StopChain(threads ...*Thread) error {
+	var errs []error
+	for _, t := range threads {
+		t.stop(false)
+		if t.Done() == nil {
+			continue
+		}
+		// Join returns early once a stop timeout expired, so wait for the
+		// termination separately to keep the order
+		awaitTermination(t)
+		if err := t.Join(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DrainOn stops the Thread once ch is closed, e.g. a channel signaling the end
+// of its input, so Runnables do not need to hardcode input-driven shutdown.
+// Combined with DrainOnStop, a consumer handles the remaining items first. As
+// receiving from ch consumes values, a value sent on it stops the Thread as
+// well. The monitoring applies to the current lifecycle only and ends when the
+// Thread terminates for another reason. It does nothing if the Thread has not
+// been started.
+func (t *Thread) DrainOn(ch <-chan struct{}) {
+	done := t.Done()
+	if done == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ch:
+			// only stop the lifecycle being monitored, checked in the same
+			// critical section so a concurrent restart is left alone
+			t.mutex.Lock()
+			if t.waitThread == done {
+				t.requestStopLocked()
+			}
+			t.mutex.Unlock()
+		case <-done:
+		}
+	}()
+}
@@ -0,0 +1,215 @@
+package thread
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	if !Sleep(make(chan bool), 10*time.Millisecond) {
+		t.Fatal("expected uninterrupted sleep to return true")
+	}
+
+	stop := make(chan bool)
+	time.AfterFunc(20*time.Millisecond, func() { close(stop) })
+	begin := time.Now()
+	if Sleep(stop, 10*time.Second) {
+		t.Fatal("expected interrupted sleep to return false")
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("expected sleep to return early, took %v", elapsed)
+	}
+}
+
+// indexRunnable marks its index as seen when run
+type indexRunnable struct {
+	index int
+	seen  []atomic.Bool
+}
+
+func (r *indexRunnable) Run(stop chan bool) error {
+	r.seen[r.index].Store(true)
+	return nil
+}
+
+func TestStartN(t *testing.T) {
+	seen := make([]atomic.Bool, 3)
+	threads := StartN(3, func(i int) Runnable {
+		return &indexRunnable{index: i, seen: seen}
+	})
+	if len(threads) != 3 {
+		t.Fatalf("expected 3 threads, got %d", len(threads))
+	}
+	for i, thread := range threads {
+		if err := thread.Join(); err != nil {
+			t.Fatalf("thread %d: unexpected error %v", i, err)
+		}
+		if !seen[i].Load() {
+			t.Fatalf("expected thread %d to run with its own index", i)
+		}
+	}
+}
+
+func TestDrainOn(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	thread.Start()
+	closed := make(chan struct{})
+	thread.DrainOn(closed)
+	time.Sleep(10 * time.Millisecond)
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected thread to keep running, got state %d", state)
+	}
+	close(closed)
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected thread to stop once the channel closed")
+	}
+}
+
+func TestDrainOnStoppedOtherwise(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	thread := New(&blockingRunnable{})
+	thread.Start()
+	thread.DrainOn(make(chan struct{}))
+	thread.StopAndJoin()
+	// the monitor must not outlive the lifecycle
+	eventually(t, func() bool { return runtime.NumGoroutine() <= baseline })
+}
+
+func TestStopChain(t *testing.T) {
+	var mutex sync.Mutex
+	var order []int
+	threads := make([]*Thread, 3)
+	for i := range threads {
+		threads[i] = New(&orderRunnable{index: i, mutex: &mutex, order: &order}).Start()
+	}
+	failed := New(&returnRunnable{err: errTemporary}).Start()
+	failed.Join()
+	never := New(&blockingRunnable{})
+	// stopped concurrently, the threads would record themselves in any order
+	if err := StopChain(threads[0], failed, never, threads[1], threads[2]); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected error of the failed thread, got %v", err)
+	}
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("expected threads to stop in order, got %v", order)
+	}
+	for i, thread := range threads {
+		if state := thread.State(); state != STOPPED {
+			t.Fatalf("expected thread %d to be stopped, got %s", i, state)
+		}
+	}
+}
+
+func TestRestartIfOlderThan(t *testing.T) {
+	var offset atomic.Int64
+	clockNow = func() time.Time {
+		return time.Now().Add(time.Duration(offset.Load()))
+	}
+	defer func() { clockNow = time.Now }()
+	thread := New(&blockingRunnable{})
+	if thread.RestartIfOlderThan(time.Hour) {
+		t.Fatal("expected a stopped thread not to be restarted")
+	}
+	thread.Start()
+	defer thread.StopAndJoin()
+	if thread.RestartIfOlderThan(time.Hour) {
+		t.Fatal("expected a young thread not to be restarted")
+	}
+	offset.Store(int64(2 * time.Hour))
+	if !thread.RestartIfOlderThan(time.Hour) {
+		t.Fatal("expected an old thread to be restarted")
+	}
+	if n := thread.StartCount(); n != 2 {
+		t.Fatalf("expected 2 starts, got %d", n)
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected restarted thread to be running, got %s", state)
+	}
+	// the uptime begins anew with the restart
+	offset.Store(int64(30 * time.Minute))
+	if thread.RestartIfOlderThan(time.Hour) {
+		t.Fatal("expected the restarted thread not to be restarted again")
+	}
+}
+
+// yieldRunnable iterates the given number of times, yielding per its budget
+type yieldRunnable struct {
+	thread     *Thread
+	iterations int
+}
+
+func (r *yieldRunnable) Run(stop chan bool) error {
+	budget := r.thread.YieldBudget()
+	counter := 0
+	for i := 0; i < r.iterations; i++ {
+		Yield(&counter, budget)
+	}
+	return nil
+}
+
+func TestYield(t *testing.T) {
+	var yields atomic.Int32
+	gosched = func() {
+		yields.Add(1)
+	}
+	defer func() { gosched = runtime.Gosched }()
+	counter := 0
+	for i := 1; i <= 10; i++ {
+		if yielded := Yield(&counter, 3); yielded != (i%3 == 0) {
+			t.Fatalf("expected to yield every 3 iterations, got %v at iteration %d", yielded, i)
+		}
+	}
+	if Yield(&counter, 0) {
+		t.Fatal("expected a zero budget never to yield")
+	}
+	yields.Store(0)
+	runnable := &yieldRunnable{iterations: 100}
+	runnable.thread = New(runnable, WithYieldBudget(10))
+	runnable.thread.Start()
+	runnable.thread.Join()
+	if n := yields.Load(); n != 10 {
+		t.Fatalf("expected 10 yields, got %d", n)
+	}
+}
+
+func TestRestartIfOlderThanStopTimeout(t *testing.T) {
+	thread := New(&slowStopRunnable{delay: 50 * time.Millisecond}, WithStopTimeout(5*time.Millisecond))
+	thread.Start()
+	defer thread.StopAndJoin()
+	// the restart waits for the slow stop despite the stop timeout
+	if !thread.RestartIfOlderThan(0) {
+		t.Fatal("expected the thread to be restarted")
+	}
+	if n := thread.StartCount(); n != 2 {
+		t.Fatalf("expected 2 starts, got %d", n)
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected restarted thread to be running, got %s", state)
+	}
+}
+
+func TestStopChainStopTimeout(t *testing.T) {
+	slow := New(&slowStopRunnable{delay: 50 * time.Millisecond}, WithStopTimeout(5*time.Millisecond)).Start()
+	next := New(&blockingRunnable{}).Start()
+	if !slow.WaitState(RUNNING, time.Second) || !next.WaitState(RUNNING, time.Second) {
+		t.Fatal("expected threads to be running")
+	}
+	var slowState State
+	stopped := make(chan struct{})
+	go func() {
+		// the next thread is only stopped once the slow one terminated
+		next.WaitState(STOPPING, time.Second)
+		slowState = slow.State()
+		close(stopped)
+	}()
+	if err := StopChain(slow, next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-stopped
+	if slowState != STOPPED {
+		t.Fatalf("expected the slow thread to have terminated first, got %s", slowState)
+	}
+}
@@ -0,0 +1,67 @@
+package thread
+
+import (
+	"sort"
+	"strings"
+)
+
+// WithLabels attaches arbitrary key/value metadata to the Thread, such as team,
+// region or shard, for filtering and logging. The labels are included in Stats
+// and Status and passed to the metrics sink if it implements
+// LabeledMetricsSink. The map is copied.
+func WithLabels(labels map[string]string) Option {
+	labels = copyLabels(labels)
+	return func(t *Thread) {
+		t.labels = labels
+	}
+}
+
+// Labels returns a copy of the labels of the Thread, or nil if it has none.
+func (t *Thread) Labels() map[string]string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return copyLabels(t.labels)
+}
+
+// LabeledMetricsSink may be implemented by a MetricsSink which supports labels.
+// The Thread calls Labeled once during initialization if it has labels, and
+// records its metrics to the returned sink instead, like curried metric
+// vectors of common metrics systems.
+type LabeledMetricsSink interface {
+	MetricsSink
+	Labeled(labels map[string]string) MetricsSink
+}
+
+// Internal helper applying the labels to the metrics sink, must be called with
+// the mutex held once all options have been applied
+func (t *Thread) labelMetricsLocked() {
+	if labeled, ok := t.metrics.(LabeledMetricsSink); ok && len(t.labels) > 0 {
+		t.metrics = labeled.Labeled(copyLabels(t.labels))
+	}
+}
+
+// Internal helper copying labels, returning nil if there are none
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Internal helper formatting labels in key order, e.g. "{region=eu,team=core}"
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,55 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// labeledSink records calls to its parent sink prefixed with its labels
+type labeledSink struct {
+	recordingSink
+	parent *labeledSink
+	labels map[string]string
+}
+
+func (s *labeledSink) Labeled(labels map[string]string) MetricsSink {
+	return &labeledSink{parent: s, labels: labels}
+}
+
+func (s *labeledSink) RecordStart(name string) {
+	s.parent.record("start %s %s", name, formatLabels(s.labels))
+}
+
+func TestLabels(t *testing.T) {
+	labels := map[string]string{"team": "core", "region": "eu"}
+	sink := &labeledSink{}
+	thread := New(&returnRunnable{}, WithName("worker"), WithLabels(labels), WithMetrics(sink))
+	labels["team"] = "changed"
+	if got := thread.Labels(); fmt.Sprint(got) != "map[region:eu team:core]" {
+		t.Fatalf("expected a copy of the labels, got %v", got)
+	}
+	thread.Labels()["region"] = "us"
+	Register(thread)
+	defer Unregister(thread)
+	var stats *Stats
+	for _, s := range Snapshot() {
+		if s.InstanceID == thread.InstanceID() {
+			stats = &s
+		}
+	}
+	if stats == nil || stats.Labels["team"] != "core" || stats.Labels["region"] != "eu" {
+		t.Fatalf("expected labels in the stats snapshot, got %+v", stats)
+	}
+	if status := thread.Status(); !strings.HasSuffix(status, " labels={region=eu,team=core}") {
+		t.Fatalf("expected labels in the status, got %q", status)
+	}
+	thread.Start()
+	thread.Join()
+	if calls := sink.recorded(); len(calls) != 1 || calls[0] != "start worker {region=eu,team=core}" {
+		t.Fatalf("expected labeled metrics, got %v", calls)
+	}
+	if labels := New(&returnRunnable{}).Labels(); labels != nil {
+		t.Fatalf("expected no labels by default, got %v", labels)
+	}
+}
@@ -0,0 +1,44 @@
+package thread
+
+// Limiter caps the number of concurrent sub-tasks of a Runnable. It is a
+// semaphore integrated with the stop signal of the Thread.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// Limit creates a Limiter allowing at most n concurrent acquisitions.
+func Limit(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is free and returns true, or returns false once
+// stop is closed. Every successful Acquire must be followed by a Release.
+func (l *Limiter) Acquire(stop chan bool) bool {
+	// prefer stopping over handing out a slot
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire. Releasing more slots than acquired
+// panics.
+func (l *Limiter) Release() {
+	select {
+	case <-l.slots:
+	default:
+		panic("thread: Release without Acquire")
+	}
+}
+
+// InUse returns the number of slots currently acquired.
+func (l *Limiter) InUse() int {
+	return len(l.slots)
+}
@@ -0,0 +1,61 @@
+package thread
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	const limit = 3
+	limiter := Limit(limit)
+	stop := make(chan bool)
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		if !limiter.Acquire(stop) {
+			t.Fatal("expected acquisition to succeed")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer limiter.Release()
+			n := active.Add(1)
+			for {
+				max := maxActive.Load()
+				if n <= max || maxActive.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			active.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if max := maxActive.Load(); max > limit {
+		t.Fatalf("expected at most %d concurrent acquisitions, got %d", limit, max)
+	}
+	if n := limiter.InUse(); n != 0 {
+		t.Fatalf("expected all slots to be released, %d in use", n)
+	}
+}
+
+func TestLimiterStop(t *testing.T) {
+	limiter := Limit(1)
+	stop := make(chan bool)
+	if !limiter.Acquire(stop) {
+		t.Fatal("expected acquisition to succeed")
+	}
+	time.AfterFunc(10*time.Millisecond, func() { close(stop) })
+	if limiter.Acquire(stop) {
+		t.Fatal("expected blocked acquisition to fail on stop")
+	}
+	limiter.Release()
+	if limiter.Acquire(stop) {
+		t.Fatal("expected acquisition to fail after stop")
+	}
+	if n := limiter.InUse(); n != 0 {
+		t.Fatalf("expected no slot in use, got %d", n)
+	}
+}
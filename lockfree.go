@@ -0,0 +1,97 @@
+package thread
+
+import (
+	"sync/atomic"
+)
+
+// LockFreeThread is a minimal variant of Thread for ultra-low-overhead use,
+// which synchronizes via atomics only instead of a mutex. It offers the basic
+// lifecycle of a Thread, Start, Stop, Join and State, but none of its options,
+// hooks or restarts.
+//
+// It is meant for a single controller goroutine: Start and Join must not be
+// called concurrently with each other or with Stop. Stop may be called by the
+// Runnable itself and State and IsStopping from any goroutine.
+type LockFreeThread struct {
+	noCopy   noCopy
+	runnable Runnable
+	state    atomic.Uint32
+	current  atomic.Pointer[lockFreeRun]
+}
+
+// Internal state of a single run of a LockFreeThread
+type lockFreeRun struct {
+	stop chan bool
+	done chan struct{}
+	err  error // written before done is closed
+}
+
+// NewLockFree creates a new LockFreeThread for the given Runnable, which must
+// be started separately using Start(). Panics with ErrNilRunnable if runnable
+// is nil.
+func NewLockFree(runnable Runnable) *LockFreeThread {
+	if runnable == nil {
+		panic(ErrNilRunnable)
+	}
+	t := &LockFreeThread{runnable: runnable}
+	// RUNNING is the zero value of State
+	t.state.Store(uint32(STOPPED))
+	return t
+}
+
+// Start starts the Runnable in a new goroutine, unless it is running already.
+// It returns the LockFreeThread for chaining.
+func (t *LockFreeThread) Start() *LockFreeThread {
+	if !t.state.CompareAndSwap(uint32(STOPPED), uint32(RUNNING)) {
+		return t
+	}
+	run := &lockFreeRun{stop: make(chan bool), done: make(chan struct{})}
+	t.current.Store(run)
+	go t.run(run)
+	return t
+}
+
+// Internal helper executing a run, panics are converted like for a Thread
+// without a custom panic handler
+func (t *LockFreeThread) run(run *lockFreeRun) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			run.err = defaultPanicHandler(recovered)
+		}
+		t.state.Store(uint32(STOPPED))
+		close(run.done)
+	}()
+	run.err = t.runnable.Run(run.stop)
+}
+
+// Stop signals the Runnable to stop, use Join to wait for it to return.
+// Stopping a LockFreeThread which is not running has no effect.
+func (t *LockFreeThread) Stop() {
+	run := t.current.Load()
+	if run != nil && t.state.CompareAndSwap(uint32(RUNNING), uint32(STOPPING)) {
+		close(run.stop)
+	}
+}
+
+// Join blocks until the most recent run returned and returns its error, which
+// is a *PanicError if the Runnable panicked. It returns nil immediately if the
+// LockFreeThread has never been started.
+func (t *LockFreeThread) Join() error {
+	run := t.current.Load()
+	if run == nil {
+		return nil
+	}
+	<-run.done
+	return run.err
+}
+
+// State returns the current state of the LockFreeThread.
+func (t *LockFreeThread) State() State {
+	return State(t.state.Load())
+}
+
+// IsStopping reports whether the LockFreeThread has been signaled to stop and
+// its Runnable has not returned yet.
+func (t *LockFreeThread) IsStopping() bool {
+	return t.State() == STOPPING
+}
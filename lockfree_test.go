@@ -0,0 +1,88 @@
+package thread
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFreeThread(t *testing.T) {
+	thread := NewLockFree(&blockingRunnable{})
+	if state := thread.State(); state != STOPPED {
+		t.Fatalf("expected new thread to be stopped, got %s", state)
+	}
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected Join of a new thread to return nil, got %v", err)
+	}
+	// observers may poll the state while the controller cycles the thread
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					thread.State()
+					thread.IsStopping()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		thread.Start()
+		if state := thread.State(); state == STOPPED {
+			t.Fatalf("expected started thread to be running, got %s", state)
+		}
+		thread.Stop()
+		if err := thread.Join(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if state := thread.State(); state != STOPPED {
+			t.Fatalf("expected joined thread to be stopped, got %s", state)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestLockFreeThreadResult(t *testing.T) {
+	thread := NewLockFree(&returnRunnable{err: errTemporary}).Start()
+	if err := thread.Join(); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected error of the run, got %v", err)
+	}
+	// stopping a thread which returned on its own has no effect
+	thread.Stop()
+	thread = NewLockFree(&panicRunnable{value: "boom"}).Start()
+	var panicErr *PanicError
+	if err := thread.Join(); !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("expected a *PanicError, got %v", err)
+	}
+	thread = NewLockFree(&sleepRunnable{duration: 10 * time.Millisecond}).Start()
+	thread.Join()
+	if thread.IsStopping() {
+		t.Fatal("expected thread not to be stopping once joined")
+	}
+}
+
+func BenchmarkThreadStartStop(b *testing.B) {
+	thread := New(&blockingRunnable{})
+	for i := 0; i < b.N; i++ {
+		thread.Start()
+		thread.Stop()
+		thread.Join()
+	}
+}
+
+func BenchmarkLockFreeThreadStartStop(b *testing.B) {
+	thread := NewLockFree(&blockingRunnable{})
+	for i := 0; i < b.N; i++ {
+		thread.Start()
+		thread.Stop()
+		thread.Join()
+	}
+}
@@ -0,0 +1,60 @@
+package thread
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger receives log messages of a Thread. It is satisfied by *log.Logger and
+// is called outside of the Thread's mutex.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithLogger sets the logger the Thread reports failed runs to. Without a
+// logger nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(t *Thread) {
+		t.logger = logger
+	}
+}
+
+// WithErrorLogThrottle limits the logging of repeated errors: an error with the
+// same message as the previous one is logged at most once per interval d. The
+// number of suppressed errors is logged along with the next error logged after
+// the interval elapsed or a different error occurred. A duration of zero or
+// less logs every error.
+func WithErrorLogThrottle(d time.Duration) Option {
+	return func(t *Thread) {
+		t.logThrottle.interval = d
+	}
+}
+
+// Throttle state of the error log
+type errorThrottle struct {
+	interval   time.Duration
+	last       string
+	lastLogged time.Time
+	suppressed int
+}
+
+// Internal helper returning the lines to log for a failed run, honoring the
+// throttle, must be called with the mutex held
+func (t *Thread) errorLogLinesLocked(err error, now time.Time) []string {
+	if t.logger == nil {
+		return nil
+	}
+	throttle := &t.logThrottle
+	message := err.Error()
+	if throttle.interval > 0 && message == throttle.last && now.Sub(throttle.lastLogged) < throttle.interval {
+		throttle.suppressed++
+		return nil
+	}
+	var lines []string
+	if throttle.suppressed > 0 {
+		lines = append(lines, fmt.Sprintf("Thread %q: suppressed %d repeated errors: %s", t.name, throttle.suppressed, throttle.last))
+	}
+	lines = append(lines, fmt.Sprintf("Thread %q: run failed: %s", t.name, message))
+	throttle.last, throttle.lastLogged, throttle.suppressed = message, now, 0
+	return lines
+}
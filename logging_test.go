@@ -0,0 +1,117 @@
+package thread
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger collects all logged lines
+type recordingLogger struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) logged() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+// delayBackoff returns the given delays in order, then zero
+type delayBackoff []time.Duration
+
+func (b delayBackoff) NextDelay(attempt int) time.Duration {
+	if attempt <= len(b) {
+		return b[attempt-1]
+	}
+	return 0
+}
+
+func (b delayBackoff) Reset() {}
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	thread := New(&countRunnable{errs: []error{errTemporary, errTemporary}},
+		WithName("worker"),
+		WithLogger(logger),
+		WithAutoRestart(-1, time.Millisecond),
+	)
+	thread.Start()
+	thread.Join()
+	eventually(t, func() bool { return len(logger.logged()) == 2 })
+	if line := logger.logged()[0]; line != `Thread "worker": run failed: temporary` {
+		t.Fatalf("unexpected log line %q", line)
+	}
+}
+
+func TestErrorLogThrottle(t *testing.T) {
+	logger := &recordingLogger{}
+	errs := make([]error, 10)
+	for i := range errs {
+		errs[i] = errTemporary
+	}
+	runnable := &countRunnable{errs: errs}
+	// five rapid failures, then one after the throttle interval elapsed
+	thread := New(runnable,
+		WithLogger(logger),
+		WithErrorLogThrottle(50*time.Millisecond),
+		WithAutoRestart(5, 0),
+		WithBackoffStrategy(delayBackoff{0, 0, 0, 0, 100 * time.Millisecond}),
+	)
+	thread.Start()
+	thread.Join()
+	eventually(t, func() bool { return len(logger.logged()) == 3 })
+	lines := logger.logged()
+	if !strings.Contains(lines[0], "run failed: temporary") {
+		t.Fatalf("expected the first error to be logged, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "suppressed 4 repeated errors: temporary") {
+		t.Fatalf("expected the suppressed count, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "run failed: temporary") {
+		t.Fatalf("expected the error after the interval to be logged, got %q", lines[2])
+	}
+}
+
+func TestErrorLogThrottleDistinctErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	thread := New(&countRunnable{errs: []error{errTemporary, ErrStopped, errTemporary}},
+		WithLogger(logger),
+		WithErrorLogThrottle(time.Hour),
+		WithAutoRestart(-1, 0),
+	)
+	thread.Start()
+	thread.Join()
+	eventually(t, func() bool { return len(logger.logged()) == 3 })
+}
+
+func TestSetDefaults(t *testing.T) {
+	logger := &recordingLogger{}
+	SetDefaults(WithLogger(logger), WithName("default"))
+	defer SetDefaults()
+
+	thread := New(&returnRunnable{err: errTemporary})
+	if name := thread.Name(); name != "default" {
+		t.Fatalf("expected the default name, got %q", name)
+	}
+	thread.Start()
+	thread.Join()
+	eventually(t, func() bool { return len(logger.logged()) == 1 })
+
+	if name := New(&blockingRunnable{}, WithName("worker")).Name(); name != "worker" {
+		t.Fatalf("expected options to override the defaults, got %q", name)
+	}
+	SetDefaults()
+	if name := New(&blockingRunnable{}).Name(); name != "" {
+		t.Fatalf("expected cleared defaults, got %q", name)
+	}
+}
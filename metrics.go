@@ -0,0 +1,37 @@
+package thread
+
+import (
+	"time"
+)
+
+// MetricsSink receives lifecycle metrics of a Thread, allowing to plug in any
+// metrics system without this package depending on it. All methods are passed
+// the name of the Thread and are called outside of its mutex.
+type MetricsSink interface {
+	// RecordStart is called whenever a run begins, including restarts
+	RecordStart(name string)
+	// RecordStop is called whenever a run ended, passing its duration
+	RecordStop(name string, d time.Duration)
+	// RecordError is called whenever a run ended with an error
+	RecordError(name string, err error)
+	// RecordRestart is called whenever a failed run is restarted automatically
+	RecordRestart(name string)
+}
+
+// WithMetrics sets the sink receiving the Thread's metrics.
+func WithMetrics(sink MetricsSink) Option {
+	return func(t *Thread) {
+		if sink == nil {
+			sink = noopMetrics{}
+		}
+		t.metrics = sink
+	}
+}
+
+// MetricsSink used if none has been set
+type noopMetrics struct{}
+
+func (noopMetrics) RecordStart(name string)                 {}
+func (noopMetrics) RecordStop(name string, d time.Duration) {}
+func (noopMetrics) RecordError(name string, err error)      {}
+func (noopMetrics) RecordRestart(name string)               {}
@@ -0,0 +1,68 @@
+package thread
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records all calls as strings
+type recordingSink struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (s *recordingSink) record(format string, args ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf(format, args...))
+}
+
+func (s *recordingSink) RecordStart(name string) {
+	s.record("start %s", name)
+}
+
+func (s *recordingSink) RecordStop(name string, d time.Duration) {
+	s.record("stop %s", name)
+}
+
+func (s *recordingSink) RecordError(name string, err error) {
+	s.record("error %s %v", name, err)
+}
+
+func (s *recordingSink) RecordRestart(name string) {
+	s.record("restart %s", name)
+}
+
+func (s *recordingSink) recorded() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func TestMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	thread := New(&countRunnable{errs: []error{errTemporary}},
+		WithName("worker"),
+		WithMetrics(sink),
+		WithAutoRestart(-1, time.Millisecond),
+	)
+	thread.Start()
+	thread.Join()
+	// the metrics of the final run are reported after Join returned
+	eventually(t, func() bool { return len(sink.recorded()) == 6 })
+
+	expected := []string{
+		"start worker",
+		"stop worker",
+		"error worker temporary",
+		"restart worker",
+		"start worker",
+		"stop worker",
+	}
+	calls := sink.recorded()
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+}
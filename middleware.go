@@ -0,0 +1,70 @@
+package thread
+
+import (
+	"runtime/debug"
+)
+
+// Middleware wraps a Runnable to add behavior around its Run method. Note that
+// the returned Runnable only implements Runnable, optional interfaces of the
+// wrapped one like ContextRunnable are not passed through.
+type Middleware func(Runnable) Runnable
+
+// Chain wraps runnable in the given middlewares. The first middleware is the
+// outermost one, so Chain(r, a, b) is equivalent to a(b(r)).
+func Chain(runnable Runnable, middlewares ...Middleware) Runnable {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		runnable = middlewares[i](runnable)
+	}
+	return runnable
+}
+
+// RecoverMiddleware returns a Middleware which recovers panics of the wrapped
+// Runnable and returns them as a *PanicError instead.
+func RecoverMiddleware() Middleware {
+	return func(runnable Runnable) Runnable {
+		return &recoverRunnable{runnable: runnable}
+	}
+}
+
+// Runnable returned by RecoverMiddleware
+type recoverRunnable struct {
+	runnable Runnable
+}
+
+func (r *recoverRunnable) Run(stop chan bool) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	return r.runnable.Run(stop)
+}
+
+// RetryMiddleware returns a Middleware which runs the wrapped Runnable again
+// if it returned an error, up to n times, and returns the result of the last
+// run. Runs are not retried once the Thread has been signaled to stop. Panics
+// are not retried unless RecoverMiddleware is chained inside of it.
+func RetryMiddleware(n int) Middleware {
+	return func(runnable Runnable) Runnable {
+		return &retryRunnable{runnable: runnable, retries: n}
+	}
+}
+
+// Runnable returned by RetryMiddleware
+type retryRunnable struct {
+	runnable Runnable
+	retries  int
+}
+
+func (r *retryRunnable) Run(stop chan bool) error {
+	err := r.runnable.Run(stop)
+	for i := 0; i < r.retries && err != nil; i++ {
+		select {
+		case <-stop:
+			return err
+		default:
+		}
+		err = r.runnable.Run(stop)
+	}
+	return err
+}
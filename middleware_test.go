@@ -0,0 +1,77 @@
+package thread
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyRunnable panics during its first runs and returns nil afterwards
+type flakyRunnable struct {
+	runs   atomic.Int32
+	panics int
+}
+
+func (r *flakyRunnable) Run(stop chan bool) error {
+	if int(r.runs.Add(1)) <= r.panics {
+		panic("flaky")
+	}
+	return nil
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	runnable := Chain(&panicRunnable{value: "boom"}, RecoverMiddleware())
+	var panicErr *PanicError
+	if err := runnable.Run(make(chan bool)); !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("expected a *PanicError with value boom, got %v", err)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	inner := &countRunnable{errs: []error{errTemporary, errTemporary, errTemporary, errTemporary}}
+	runnable := Chain(inner, RetryMiddleware(2))
+	if err := runnable.Run(make(chan bool)); err != errTemporary {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if runs := inner.runs.Load(); runs != 3 {
+		t.Fatalf("expected 3 runs, got %d", runs)
+	}
+}
+
+func TestRetryMiddlewareStopped(t *testing.T) {
+	inner := &countRunnable{errs: []error{errTemporary, errTemporary}}
+	stop := make(chan bool)
+	close(stop)
+	if err := Chain(inner, RetryMiddleware(2)).Run(stop); err != errTemporary {
+		t.Fatalf("expected the first error, got %v", err)
+	}
+	if runs := inner.runs.Load(); runs != 1 {
+		t.Fatalf("expected no retries after stop, got %d runs", runs)
+	}
+}
+
+func TestChainRecoverAndRetry(t *testing.T) {
+	inner := &flakyRunnable{panics: 2}
+	thread := New(Chain(inner, RetryMiddleware(2), RecoverMiddleware()))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected panics to be recovered and retried, got %v", err)
+	}
+	if runs := inner.runs.Load(); runs != 3 {
+		t.Fatalf("expected 3 runs, got %d", runs)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(runnable Runnable) Runnable {
+			order = append(order, name)
+			return runnable
+		}
+	}
+	Chain(&countRunnable{}, trace("outer"), trace("inner"))
+	if len(order) != 2 || order[0] != "inner" || order[1] != "outer" {
+		t.Fatalf("expected inner to wrap first, got %v", order)
+	}
+}
@@ -0,0 +1,36 @@
+package thread
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned by Thread.Join() if the Runnable panicked and no
+// custom panic handler has been set.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Thread panicked: %v", e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error, nil otherwise.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Internal record of a panic recovered from a Runnable
+type recoveredPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// Panic handler used if none has been set via the WithPanicHandler option
+func defaultPanicHandler(recovered interface{}) error {
+	return &PanicError{Value: recovered, Stack: debug.Stack()}
+}
@@ -0,0 +1,114 @@
+package thread
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicRunnable panics with the given value as soon as it is run
+type panicRunnable struct {
+	value interface{}
+}
+
+func (r *panicRunnable) Run(stop chan bool) error {
+	panic(r.value)
+}
+
+func TestDefaultPanicHandler(t *testing.T) {
+	thread := New(&panicRunnable{value: "boom"})
+	thread.Start()
+	err := thread.Join()
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a *PanicError, got %v", err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected value and stack to be recorded, got %+v", panicErr)
+	}
+}
+
+func TestSwallowingPanicHandler(t *testing.T) {
+	var recovered interface{}
+	thread := New(&panicRunnable{value: "boom"}, WithPanicHandler(func(r interface{}) error {
+		recovered = r
+		return nil
+	}))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected panic to be swallowed, got %v", err)
+	}
+	if recovered != "boom" {
+		t.Fatalf("expected handler to receive the panic value, got %v", recovered)
+	}
+}
+
+func TestRepanickingPanicHandler(t *testing.T) {
+	// a propagating panic crashes the process, so run it in a child process
+	if os.Getenv("THREAD_TEST_REPANIC") == "1" {
+		thread := New(&panicRunnable{value: "boom"}, WithPanicHandler(func(r interface{}) error {
+			panic(r)
+		}))
+		thread.Start()
+		thread.Join()
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRepanickingPanicHandler$")
+	cmd.Env = append(os.Environ(), "THREAD_TEST_REPANIC=1")
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("expected child process to crash")
+	}
+	if !strings.Contains(string(output), "panic: boom") {
+		t.Fatalf("expected propagated panic in output, got:\n%s", output)
+	}
+}
+
+func TestOnPanicAndOnError(t *testing.T) {
+	panics := make(chan []byte, 1)
+	errs := make(chan error, 1)
+	hooks := []Option{
+		WithOnPanic(func(recovered interface{}, stack []byte) {
+			panics <- stack
+		}),
+		WithOnError(func(err error) {
+			errs <- err
+		}),
+	}
+
+	// hooks may run after Join returned, so wait for them
+	thread := New(&panicRunnable{value: "boom"}, hooks...)
+	thread.Start()
+	thread.Join()
+	select {
+	case stack := <-panics:
+		if len(stack) == 0 {
+			t.Fatal("expected OnPanic to receive a stack trace")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected OnPanic to be called")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("expected OnError not to be called for a panic, got %v", err)
+	default:
+	}
+
+	thread = New(&returnRunnable{err: errors.New("failed")}, hooks...)
+	thread.Start()
+	thread.Join()
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("expected OnError to be called")
+	}
+	select {
+	case <-panics:
+		t.Fatal("expected OnPanic not to be called for a returned error")
+	default:
+	}
+}
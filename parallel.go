@@ -0,0 +1,75 @@
+package thread
+
+import (
+	"errors"
+)
+
+// ParallelOption configures the Runnable returned by Parallel.
+type ParallelOption func(*parallel)
+
+// SequentialStop makes Parallel stop its sub-runnables one at a time in reverse
+// order, signaling the next one only after the previous one returned. This
+// suits sub-runnables depending on the ones started before them, at the cost
+// of a slower shutdown, as the individual stop durations add up.
+func SequentialStop() ParallelOption {
+	return func(p *parallel) {
+		p.sequentialStop = true
+	}
+}
+
+// Parallel returns a Runnable running all given runnables concurrently, each
+// with its own stop channel. Its Run method returns once all of them returned.
+// If the Thread is stopped or a sub-runnable fails, all remaining ones are
+// signaled to stop. The errors of all sub-runnables are joined together.
+// Panics of sub-runnables are not recovered, use RecoverMiddleware for that.
+func Parallel(runnables []Runnable, opts ...ParallelOption) Runnable {
+	p := &parallel{runnables: append([]Runnable(nil), runnables...)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Runnable returned by Parallel
+type parallel struct {
+	runnables      []Runnable
+	sequentialStop bool
+}
+
+func (p *parallel) Run(stop chan bool) error {
+	stops := make([]chan bool, len(p.runnables))
+	dones := make([]chan struct{}, len(p.runnables))
+	errs := make([]error, len(p.runnables))
+	failed := make(chan struct{}, len(p.runnables))
+	for i, runnable := range p.runnables {
+		stops[i] = make(chan bool)
+		dones[i] = make(chan struct{})
+		go func(i int, runnable Runnable) {
+			defer close(dones[i])
+			if errs[i] = runnable.Run(stops[i]); errs[i] != nil {
+				failed <- struct{}{}
+			}
+		}(i, runnable)
+	}
+	all := make(chan struct{})
+	go func() {
+		for _, done := range dones {
+			<-done
+		}
+		close(all)
+	}()
+	select {
+	case <-stop:
+	case <-failed:
+	case <-all:
+	}
+	// stop in reverse start order
+	for i := len(stops) - 1; i >= 0; i-- {
+		close(stops[i])
+		if p.sequentialStop {
+			<-dones[i]
+		}
+	}
+	<-all
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package thread
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderRunnable records its index once stopped, taking a while to shut down
+type orderRunnable struct {
+	index int
+	mutex *sync.Mutex
+	order *[]int
+}
+
+func (r *orderRunnable) Run(stop chan bool) error {
+	<-stop
+	time.Sleep(5 * time.Millisecond)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	*r.order = append(*r.order, r.index)
+	return nil
+}
+
+func TestParallel(t *testing.T) {
+	first := &countRunnable{}
+	second := &countRunnable{}
+	thread := New(Parallel([]Runnable{first, second}))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.runs.Load() != 1 || second.runs.Load() != 1 {
+		t.Fatal("expected every sub-runnable to run once")
+	}
+}
+
+func TestParallelError(t *testing.T) {
+	thread := New(Parallel([]Runnable{&blockingRunnable{}, &returnRunnable{err: errTemporary}}))
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected the failure to stop the others, got %v", err)
+	}
+}
+
+func TestParallelSequentialStop(t *testing.T) {
+	var mutex sync.Mutex
+	var order []int
+	runnables := make([]Runnable, 3)
+	for i := range runnables {
+		runnables[i] = &orderRunnable{index: i, mutex: &mutex, order: &order}
+	}
+	thread := New(Parallel(runnables, SequentialStop()))
+	thread.Start()
+	time.Sleep(10 * time.Millisecond)
+	if err := thread.StopAndJoin(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order) != 3 || order[0] != 2 || order[1] != 1 || order[2] != 0 {
+		t.Fatalf("expected reverse stop order [2 1 0], got %v", order)
+	}
+}
@@ -0,0 +1,53 @@
+package thread
+
+import (
+	"errors"
+)
+
+// ErrNotQuiescable is returned by Thread.Quiesce() and Thread.Unquiesce() if the
+// Runnable does not implement Quiescable.
+var ErrNotQuiescable = errors.New("Runnable does not support quiescing")
+
+// Quiescable may be implemented by a Runnable which can be put into a quiescent
+// mode, in which it stops processing new work but keeps running and holds on to
+// its resources, e.g. keeping connections warm and heartbeats alive. Unlike a
+// stopped Runnable it can resume work immediately once unquiesced.
+type Quiescable interface {
+	SetQuiesced(quiesced bool)
+}
+
+// Quiesce puts the Runnable into quiescent mode, see Quiescable. The Thread
+// keeps running. It returns ErrNotQuiescable if the Runnable does not support
+// it.
+func (t *Thread) Quiesce() error {
+	return t.setQuiesced(true)
+}
+
+// Unquiesce makes a quiesced Runnable resume processing work. It returns
+// ErrNotQuiescable if the Runnable does not support it.
+func (t *Thread) Unquiesce() error {
+	return t.setQuiesced(false)
+}
+
+// Quiesced reports whether the Runnable has been put into quiescent mode.
+func (t *Thread) Quiesced() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.quiesced
+}
+
+// Internal helper signaling the quiescent mode to the runnable, which is done
+// outside of the mutex
+func (t *Thread) setQuiesced(quiesced bool) error {
+	t.mutex.Lock()
+	runnable, ok := t.runnable.(Quiescable)
+	if ok {
+		t.quiesced = quiesced
+	}
+	t.mutex.Unlock()
+	if !ok {
+		return ErrNotQuiescable
+	}
+	runnable.SetQuiesced(quiesced)
+	return nil
+}
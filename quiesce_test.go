@@ -0,0 +1,69 @@
+package thread
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// quiesceRunnable counts the work it processes unless quiesced
+type quiesceRunnable struct {
+	quiesced  atomic.Bool
+	processed atomic.Int32
+}
+
+func (r *quiesceRunnable) SetQuiesced(quiesced bool) {
+	r.quiesced.Store(quiesced)
+}
+
+func (r *quiesceRunnable) Run(stop chan bool) error {
+	for Sleep(stop, time.Millisecond) {
+		if !r.quiesced.Load() {
+			r.processed.Add(1)
+		}
+	}
+	return nil
+}
+
+func TestQuiesce(t *testing.T) {
+	runnable := &quiesceRunnable{}
+	thread := New(runnable)
+	thread.Start()
+	defer thread.StopAndJoin()
+	eventually(t, func() bool { return runnable.processed.Load() > 0 })
+
+	if err := thread.Quiesce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !runnable.quiesced.Load() || !thread.Quiesced() {
+		t.Fatal("expected runnable to be quiesced")
+	}
+	// allow for an iteration in progress while quiescing
+	time.Sleep(5 * time.Millisecond)
+	processed := runnable.processed.Load()
+	time.Sleep(20 * time.Millisecond)
+	if n := runnable.processed.Load(); n != processed {
+		t.Fatalf("expected no work while quiesced, got %d more", n-processed)
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected thread to keep running, got state %d", state)
+	}
+
+	if err := thread.Unquiesce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if thread.Quiesced() {
+		t.Fatal("expected thread to be unquiesced")
+	}
+	eventually(t, func() bool { return runnable.processed.Load() > processed })
+}
+
+func TestQuiesceUnsupported(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	if err := thread.Quiesce(); err != ErrNotQuiescable {
+		t.Fatalf("expected ErrNotQuiescable, got %v", err)
+	}
+	if thread.Quiesced() {
+		t.Fatal("expected thread not to be quiesced")
+	}
+}
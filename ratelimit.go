@@ -0,0 +1,50 @@
+package thread
+
+import (
+	"sync"
+	"time"
+)
+
+// Process wide limit of run starts, see SetGlobalStartRateLimit
+var startRate struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// SetGlobalStartRateLimit limits the number of runs started per second across
+// all Threads of the process, protecting shared resources during mass
+// restarts. Starts and restarts beyond the limit are spaced out evenly: the
+// goroutine of the Thread waits for its slot before calling the Runnable, while
+// Start itself never blocks. A stop during the wait skips the run. A value of
+// zero or less removes the limit, which is the default.
+func SetGlobalStartRateLimit(perSecond int) {
+	startRate.mutex.Lock()
+	defer startRate.mutex.Unlock()
+	startRate.interval = 0
+	startRate.next = time.Time{}
+	if perSecond > 0 {
+		startRate.interval = time.Second / time.Duration(perSecond)
+	}
+}
+
+// Internal helper reserving the next start slot and waiting for it. It returns
+// false if stop is closed before the slot is reached.
+func awaitStartSlot(stop chan bool) bool {
+	startRate.mutex.Lock()
+	if startRate.interval == 0 {
+		startRate.mutex.Unlock()
+		return true
+	}
+	now := time.Now()
+	slot := startRate.next
+	if slot.Before(now) {
+		slot = now
+	}
+	startRate.next = slot.Add(startRate.interval)
+	startRate.mutex.Unlock()
+	if wait := slot.Sub(now); wait > 0 {
+		return Sleep(stop, wait)
+	}
+	return true
+}
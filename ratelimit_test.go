@@ -0,0 +1,77 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+// timeRunnable reports the time it started, then runs until stopped
+type timeRunnable struct {
+	started chan time.Time
+}
+
+func (r *timeRunnable) Run(stop chan bool) error {
+	r.started <- time.Now()
+	<-stop
+	return nil
+}
+
+func TestGlobalStartRateLimit(t *testing.T) {
+	const perSecond, n = 50, 10
+	SetGlobalStartRateLimit(perSecond)
+	defer SetGlobalStartRateLimit(0)
+	started := make(chan time.Time, n)
+	threads := make([]*Thread, n)
+	begin := time.Now()
+	for i := range threads {
+		threads[i] = New(&timeRunnable{started: started}).Start()
+	}
+	if elapsed := time.Since(begin); elapsed > 10*time.Millisecond {
+		t.Fatalf("expected Start not to block, took %v", elapsed)
+	}
+	var first, last time.Time
+	for i := 0; i < n; i++ {
+		at := <-started
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	// n starts take at least n-1 intervals, allow timer imprecision
+	min := time.Duration(n-1) * time.Second / perSecond
+	if spread := last.Sub(first); spread < min-5*time.Millisecond {
+		t.Fatalf("expected %d starts to take at least %v, took %v", n, min, spread)
+	}
+	for _, thread := range threads {
+		thread.Stop()
+		thread.Join()
+	}
+}
+
+func TestGlobalStartRateLimitStop(t *testing.T) {
+	SetGlobalStartRateLimit(1)
+	defer SetGlobalStartRateLimit(0)
+	started := make(chan time.Time, 2)
+	first := New(&timeRunnable{started: started}).Start()
+	<-started
+	// the second start has to wait for a second, a stop skips it
+	second := New(&timeRunnable{started: started}).Start()
+	second.Stop()
+	done := make(chan struct{})
+	go func() {
+		second.Join()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("expected stop to interrupt the wait for a start slot")
+	}
+	if len(started) != 0 {
+		t.Fatal("expected the stopped Thread not to run")
+	}
+	first.Stop()
+	first.Join()
+}
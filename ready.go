@@ -0,0 +1,93 @@
+package thread
+
+import (
+	"sync"
+	"time"
+)
+
+// ReadyRunnable may be implemented by a Runnable which needs some time before
+// it is operational, e.g. to open connections. The Thread then calls RunReady
+// instead of Run and the Runnable calls ready once it is able to do its work.
+// Calling ready more than once has no effect. Runnables not implementing this
+// interface are considered ready as soon as they run.
+type ReadyRunnable interface {
+	RunReady(stop chan bool, ready func()) error
+}
+
+// Internal helper returning the ready function closing the given channel and
+// calling the hook, if any
+func readyFunc(ready chan struct{}, hook func()) func() {
+	return sync.OnceFunc(func() {
+		close(ready)
+		if hook != nil {
+			hook()
+		}
+	})
+}
+
+// WithOnReady sets a hook called once per run as soon as the Runnable signaled
+// readiness, a push-based alternative to WaitReady, e.g. to register endpoints
+// exactly when a worker is able to serve them. The hook is called outside of
+// the mutex from the goroutine signaling readiness, for Runnables not
+// implementing ReadyRunnable right before they run.
+func WithOnReady(hook func()) Option {
+	return func(t *Thread) {
+		t.onReady = hook
+	}
+}
+
+// WaitReady blocks until the current run of the Thread signaled readiness and
+// returns nil if it did so within the timeout. Otherwise it returns
+// ErrNotStarted if the Thread has never been started, ErrStoppedBeforeReady if
+// it is stopped or stops while waiting, and ErrReadyTimeout if the timeout
+// elapsed first.
+func (t *Thread) WaitReady(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	return t.awaitReady(timer.C)
+}
+
+// Internal helper waiting for readiness until timeout fires, a nil timeout
+// waits forever. Restarts replace the ready channel, so it is read again after
+// every state change.
+func (t *Thread) awaitReady(timeout <-chan time.Time) error {
+	for {
+		t.mutex.Lock()
+		state, ready, done := t.state, t.ready, t.waitThread
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		t.mutex.Unlock()
+		if done == nil {
+			return ErrNotStarted
+		}
+		if state == STOPPED {
+			return ErrStoppedBeforeReady
+		}
+		select {
+		case <-ready:
+			return nil
+		case <-done:
+			return ErrStoppedBeforeReady
+		case <-changed:
+		case <-timeout:
+			return ErrReadyTimeout
+		}
+	}
+}
+
+// Await waits until the Thread has been started and its Runnable is ready.
+// Runnables not implementing ReadyRunnable are ready as soon as their run
+// begins, so for them Await confirms the start. It returns the same errors as
+// WaitReady and is meant to be chained, e.g. New(r).Start().Await(timeout).
+func (t *Thread) Await(timeout time.Duration) error {
+	return t.WaitReady(timeout)
+}
+
+// StartAndWaitReady starts the Thread and waits until it is ready, see
+// WaitReady for the errors returned.
+func (t *Thread) StartAndWaitReady(timeout time.Duration) error {
+	t.Start()
+	return t.WaitReady(timeout)
+}
@@ -0,0 +1,164 @@
+package thread
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// readyRunnable signals readiness after the given delay, then runs until stopped
+type readyRunnable struct {
+	delay time.Duration
+}
+
+func (r *readyRunnable) Run(stop chan bool) error {
+	panic("Run must not be called for a ReadyRunnable")
+}
+
+func (r *readyRunnable) RunReady(stop chan bool, ready func()) error {
+	if !Sleep(stop, r.delay) {
+		return nil
+	}
+	ready()
+	ready()
+	<-stop
+	return nil
+}
+
+func TestStartAndWaitReady(t *testing.T) {
+	thread := New(&readyRunnable{delay: 20 * time.Millisecond})
+	begin := time.Now()
+	if err := thread.StartAndWaitReady(time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected to wait for readiness, returned after %v", elapsed)
+	}
+	thread.Stop()
+	thread.Join()
+}
+
+func TestStartAndWaitReadyTimeout(t *testing.T) {
+	thread := New(&readyRunnable{delay: time.Second})
+	if err := thread.StartAndWaitReady(10 * time.Millisecond); err != ErrReadyTimeout {
+		t.Fatalf("expected ErrReadyTimeout, got %v", err)
+	}
+	thread.Stop()
+	thread.Join()
+}
+
+// failReadyRunnable fails its first run before signaling readiness, later runs
+// are ready right away and run until stopped
+type failReadyRunnable struct {
+	runs atomic.Int32
+}
+
+func (r *failReadyRunnable) Run(stop chan bool) error {
+	panic("Run must not be called for a ReadyRunnable")
+}
+
+func (r *failReadyRunnable) RunReady(stop chan bool, ready func()) error {
+	if r.runs.Add(1) == 1 {
+		return errTemporary
+	}
+	ready()
+	<-stop
+	return nil
+}
+
+func TestWaitReadyAfterRestart(t *testing.T) {
+	runnable := &failReadyRunnable{}
+	thread := New(runnable, WithAutoRestart(-1, 10*time.Millisecond))
+	if err := thread.StartAndWaitReady(time.Second); err != nil {
+		t.Fatalf("expected the restarted run to become ready, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", runs)
+	}
+	thread.StopAndJoin()
+}
+
+func TestWaitReadyPlainRunnable(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	thread.Start()
+	if err := thread.WaitReady(time.Second); err != nil {
+		t.Fatalf("expected running thread to be ready, got %v", err)
+	}
+	thread.Stop()
+	thread.Join()
+}
+
+func TestWaitReadyNotStarted(t *testing.T) {
+	thread := New(&readyRunnable{})
+	if err := thread.WaitReady(time.Second); err != ErrNotStarted {
+		t.Fatalf("expected ErrNotStarted, got %v", err)
+	}
+}
+
+func TestWaitReadyStoppedBeforeReady(t *testing.T) {
+	thread := New(&readyRunnable{delay: time.Second})
+	thread.Start()
+	time.AfterFunc(10*time.Millisecond, thread.Stop)
+	if err := thread.WaitReady(time.Second); err != ErrStoppedBeforeReady {
+		t.Fatalf("expected ErrStoppedBeforeReady, got %v", err)
+	}
+	thread.Join()
+	// waiting on a stopped thread fails right away
+	if err := thread.WaitReady(time.Second); err != ErrStoppedBeforeReady {
+		t.Fatalf("expected ErrStoppedBeforeReady, got %v", err)
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	thread := New(&readyRunnable{delay: time.Second})
+	thread.Start()
+	if err := thread.WaitReady(10 * time.Millisecond); err != ErrReadyTimeout {
+		t.Fatalf("expected ErrReadyTimeout, got %v", err)
+	}
+	thread.Stop()
+	thread.Join()
+}
+
+func TestStartAwait(t *testing.T) {
+	thread := New(&readyRunnable{delay: 10 * time.Millisecond}, WithName("worker"))
+	if err := thread.Start().Await(time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected thread to be running, got state %d", state)
+	}
+	thread.StopAndJoin()
+
+	if err := New(&blockingRunnable{}).Await(time.Second); err != ErrNotStarted {
+		t.Fatalf("expected ErrNotStarted without Start, got %v", err)
+	}
+}
+
+func TestOnReady(t *testing.T) {
+	fired := make(chan error, 4)
+	var thread *Thread
+	thread = New(&readyRunnable{delay: 20 * time.Millisecond}, WithOnReady(func() {
+		// readiness has been signaled before the hook is called
+		fired <- thread.WaitReady(time.Second)
+	}))
+	for i := 0; i < 2; i++ {
+		begin := time.Now()
+		thread.Start()
+		select {
+		case err := <-fired:
+			if err != nil {
+				t.Fatalf("expected thread to be ready when the hook fires, got %v", err)
+			}
+			if elapsed := time.Since(begin); elapsed < 20*time.Millisecond {
+				t.Fatalf("expected hook to fire once ready, fired after %v", elapsed)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected hook to fire")
+		}
+		thread.Stop()
+		thread.Join()
+	}
+	if len(fired) != 0 {
+		t.Fatal("expected hook to fire once per run")
+	}
+}
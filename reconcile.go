@@ -0,0 +1,55 @@
+package thread
+
+// RequestRunning records whether the Thread should be running and leaves the
+// transitions to a background reconciler, so that rapid toggles converge to
+// the last request instead of depending on timing, e.g. Start() having no
+// effect while a previous run is still stopping.
+//
+// The reconciliation model: the reconciler compares the requested to the
+// actual state and drives the Thread towards it, starting it if it is stopped,
+// stopping it if it is running, and waiting for the Runnable to return while it
+// is stopping. Requests made meanwhile replace the previous one, intermediate
+// requests may therefore never take effect. Once the actual state matches the
+// last request the reconciler ends. It does not keep enforcing the request
+// afterwards: a Runnable returning on its own, or direct calls to Start() and
+// Stop(), are not reverted.
+func (t *Thread) RequestRunning(running bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.desired = running
+	if !t.reconciling {
+		t.reconciling = true
+		go t.reconcile()
+	}
+}
+
+// Internal helper driving the Thread towards the requested state until it
+// matches
+func (t *Thread) reconcile() {
+	for {
+		t.mutex.Lock()
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		switch {
+		case t.desired && t.state == STOPPED:
+			go t.run(t.startLocked())
+		case !t.desired && t.state == RUNNING:
+			// behaves like Stop
+			t.replace = false
+			t.cancelStartLocked()
+			t.stopLocked(ErrStopped)
+		case t.state == STOPPING:
+			// wait for the runnable to return, whatever has been requested
+			t.mutex.Unlock()
+			<-changed
+			continue
+		default:
+			t.reconciling = false
+			t.mutex.Unlock()
+			return
+		}
+		t.mutex.Unlock()
+	}
+}
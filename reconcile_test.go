@@ -0,0 +1,29 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestRunning(t *testing.T) {
+	thread := New(&slowStopRunnable{delay: 5 * time.Millisecond})
+	for round, final := range []bool{true, false, true, false} {
+		// toggle faster than the runnable stops
+		for i := 0; i < 50; i++ {
+			thread.RequestRunning(i%2 == 0)
+		}
+		thread.RequestRunning(final)
+		expected := STOPPED
+		if final {
+			expected = RUNNING
+		}
+		eventually(t, func() bool { return thread.State() == expected })
+		time.Sleep(20 * time.Millisecond)
+		if state := thread.State(); state != expected {
+			t.Fatalf("round %d: expected state %s to match the last request, got %s", round, expected, state)
+		}
+	}
+	if thread.StartCount() < 2 {
+		t.Fatalf("expected the thread to have been started repeatedly, got %d starts", thread.StartCount())
+	}
+}
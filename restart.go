@@ -0,0 +1,142 @@
+package thread
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRestart may be returned by a Runnable to have it run again right away,
+// even without WithAutoRestart(). Such restarts neither count against the
+// restart limit nor are they delayed, and the run is otherwise treated as if
+// it returned nil. A Thread which has been signaled to stop is not restarted.
+var ErrRestart = errors.New("restart requested")
+
+// Upper bound of the delay between two automatic restarts
+const maxBackoff = time.Minute
+
+// WithAutoRestart makes the Thread restart its Runnable whenever a run fails
+// with an error, including recovered panics. Runs stopped via Stop() are not
+// restarted, nor are runs returning nil unless WithRestartOnCleanExit is set.
+// Consecutive restarts are delayed exponentially, starting at backoff and
+// doubling up to a minute. At most maxRestarts restarts happen per Start(), a
+// negative value means no limit.
+func WithAutoRestart(maxRestarts int, backoff time.Duration) Option {
+	return func(t *Thread) {
+		t.autoRestart = true
+		t.maxRestarts = maxRestarts
+		t.backoff = backoff
+	}
+}
+
+// WithErrorClassifier sets the function deciding whether a failed run may be
+// restarted. Returning true means restartable, false means fatal, in which case
+// the Thread stops and Join() returns the error. Without a classifier all
+// errors are restartable.
+func WithErrorClassifier(classifier func(err error) bool) Option {
+	return func(t *Thread) {
+		t.classifier = classifier
+	}
+}
+
+// WithRecoverLimit stops automatic restarts once n panics have been recovered
+// since Start(). The Thread then stops with the result of the panic handler
+// for the last panic, a PanicError by default, being returned by Join(). A
+// limit of zero or less disables the check.
+func WithRecoverLimit(n int) Option {
+	return func(t *Thread) {
+		t.recoverLimit = n
+	}
+}
+
+// WithMinRunTime sets the minimum duration of a healthy run. With automatic
+// restarts enabled, a run ending sooner is treated as failed even if it
+// returned nil, so it is restarted with backoff instead of the Thread stopping
+// or spinning in a tight loop. A duration of zero or less disables the check.
+func WithMinRunTime(d time.Duration) Option {
+	return func(t *Thread) {
+		t.minRunTime = d
+	}
+}
+
+// WithRestartOnCleanExit chooses what a run returning nil on its own means with
+// automatic restarts enabled. In task mode, the default, the work is done and
+// the Thread stops. In daemon mode, enabled by passing true, the Runnable is
+// not supposed to ever return, so the run is restarted like a failed one,
+// with backoff and counting against the restart limit. Runs stopped via Stop()
+// are never restarted. Without WithAutoRestart the option has no effect.
+func WithRestartOnCleanExit(restart bool) Option {
+	return func(t *Thread) {
+		t.restartOnCleanExit = restart
+	}
+}
+
+// Internal helper checking whether the last run ended before the minimum run
+// time, must be called with the mutex held after the run ended
+func (t *Thread) shortRunLocked() bool {
+	return t.minRunTime > 0 && t.runStopped.Sub(t.runStarted) < t.minRunTime
+}
+
+// IsRestarting reports whether a run failed and the Thread is waiting for the
+// backoff delay to pass before restarting it. State() reports RUNNING during
+// that time.
+func (t *Thread) IsRestarting() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.inBackoff && t.state == RUNNING
+}
+
+// RestartDelay returns the backoff delay of the pending restart, or zero if the
+// Thread is not waiting for one, see IsRestarting().
+func (t *Thread) RestartDelay() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.inBackoff || t.state != RUNNING {
+		return 0
+	}
+	return t.backoffDelay
+}
+
+// Internal helper checking whether the recover limit has been reached, must be
+// called with the mutex held
+func (t *Thread) recoverLimitReachedLocked() bool {
+	return t.recoverLimit > 0 && t.panics >= t.recoverLimit
+}
+
+// Internal helper checking whether the given run result may be restarted, the
+// classifier is called outside of the mutex
+func (t *Thread) restartable(err error) bool {
+	t.mutex.Lock()
+	autoRestart, classifier := t.autoRestart, t.classifier
+	t.mutex.Unlock()
+	if !autoRestart || err == nil {
+		return false
+	}
+	return classifier == nil || classifier(err)
+}
+
+// Internal helper returning the backoff strategy in use, must be called with
+// the mutex held
+func (t *Thread) backoffStrategyLocked() BackoffStrategy {
+	if t.strategy == nil {
+		return ExponentialBackoff{Initial: t.backoff, Max: maxBackoff}
+	}
+	return t.strategy
+}
+
+// Internal helper resetting the consecutive failure count and the backoff
+// strategy, must be called with the mutex held
+func (t *Thread) resetBackoffLocked() {
+	t.failures = 0
+	t.backoffStrategyLocked().Reset()
+}
+
+// Internal helper counting a restart if the limit allows another one and
+// returning its backoff delay, must be called with the mutex held
+func (t *Thread) restartLocked() (bool, time.Duration) {
+	if t.maxRestarts >= 0 && t.restarts >= t.maxRestarts {
+		return false, 0
+	}
+	t.restarts++
+	t.failures++
+	return true, t.backoffStrategyLocked().NextDelay(t.failures)
+}
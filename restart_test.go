@@ -0,0 +1,245 @@
+package thread
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countRunnable counts its runs, returning the error at the index of the run
+// or nil once all errors have been returned
+type countRunnable struct {
+	runs atomic.Int32
+	errs []error
+}
+
+func (r *countRunnable) Run(stop chan bool) error {
+	run := int(r.runs.Add(1)) - 1
+	if run < len(r.errs) {
+		return r.errs[run]
+	}
+	return nil
+}
+
+var errTemporary = errors.New("temporary")
+
+func TestAutoRestart(t *testing.T) {
+	runnable := &countRunnable{errs: []error{errTemporary, errTemporary}}
+	thread := New(runnable, WithAutoRestart(-1, time.Millisecond))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected final run to succeed, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 3 {
+		t.Fatalf("expected 3 runs, got %d", runs)
+	}
+}
+
+func TestAutoRestartLimit(t *testing.T) {
+	runnable := &countRunnable{errs: []error{errTemporary, errTemporary, errTemporary, errTemporary}}
+	thread := New(runnable, WithAutoRestart(2, time.Millisecond))
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected error of the last run, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 3 {
+		t.Fatalf("expected 1 run and 2 restarts, got %d runs", runs)
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	errFatal := errors.New("fatal")
+	runnable := &countRunnable{errs: []error{errTemporary, errFatal, errTemporary}}
+	thread := New(runnable,
+		WithAutoRestart(-1, time.Millisecond),
+		WithErrorClassifier(func(err error) bool {
+			return !errors.Is(err, errFatal)
+		}),
+	)
+	thread.Start()
+	if err := thread.Join(); !errors.Is(err, errFatal) {
+		t.Fatalf("expected fatal error, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 2 {
+		t.Fatalf("expected no restart after the fatal error, got %d runs", runs)
+	}
+}
+
+// panicCountRunnable counts its runs and panics in each of them
+type panicCountRunnable struct {
+	runs atomic.Int32
+}
+
+func (r *panicCountRunnable) Run(stop chan bool) error {
+	r.runs.Add(1)
+	panic("boom")
+}
+
+func TestRecoverLimit(t *testing.T) {
+	runnable := &panicCountRunnable{}
+	thread := New(runnable, WithAutoRestart(-1, time.Millisecond), WithRecoverLimit(2))
+	thread.Start()
+	err := thread.Join()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected last PanicError, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 2 {
+		t.Fatalf("expected thread to stop after 2 panics, got %d runs", runs)
+	}
+}
+
+func TestMinRunTime(t *testing.T) {
+	runnable := &countRunnable{}
+	thread := New(runnable, WithAutoRestart(-1, 20*time.Millisecond), WithMinRunTime(time.Second))
+	thread.Start()
+	time.Sleep(100 * time.Millisecond)
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// 20ms, 40ms and 80ms of backoff fit into the window, a spinning thread
+	// would have run many more times
+	if runs := runnable.runs.Load(); runs < 2 || runs > 5 {
+		t.Fatalf("expected the thread to back off, got %d runs", runs)
+	}
+}
+
+func TestMinRunTimeNoAutoRestart(t *testing.T) {
+	runnable := &countRunnable{}
+	thread := New(runnable, WithMinRunTime(time.Second))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 1 {
+		t.Fatalf("expected 1 run, got %d", runs)
+	}
+}
+
+func TestErrRestart(t *testing.T) {
+	runnable := &countRunnable{errs: []error{ErrRestart}}
+	thread := New(runnable)
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", runs)
+	}
+}
+
+func TestErrRestartNotCounted(t *testing.T) {
+	runnable := &countRunnable{errs: []error{ErrRestart, ErrRestart, errTemporary, nil}}
+	thread := New(runnable, WithAutoRestart(1, time.Hour), WithBackoffStrategy(ConstantBackoff{Delay: time.Millisecond}))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected the error restart to be within the limit, got %v", err)
+	}
+	if runs := runnable.runs.Load(); runs != 4 {
+		t.Fatalf("expected 4 runs, got %d", runs)
+	}
+}
+
+// failRunnable always fails, counting its runs
+type failRunnable struct {
+	runs atomic.Int32
+}
+
+func (r *failRunnable) Run(stop chan bool) error {
+	r.runs.Add(1)
+	return errTemporary
+}
+
+func TestStopRacingRestart(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		runnable := &failRunnable{}
+		// bound the restarts, so the events of a tight restart loop without
+		// backoff fit into the buffer
+		thread := New(runnable,
+			WithAutoRestart(1000, 0),
+			WithBackoffStrategy(ConstantBackoff{Delay: time.Duration(i%4) * 100 * time.Microsecond}),
+			WithEventBuffer(1<<16, DropOldest),
+		)
+		events := thread.Events()
+		thread.Start()
+		time.Sleep(time.Duration(i%7) * 50 * time.Microsecond)
+		thread.Stop()
+		stoppedAt := time.Now()
+		if !thread.WaitState(STOPPED, time.Second) {
+			t.Fatalf("iteration %d: expected thread to stop", i)
+		}
+		if err := thread.Join(); err != nil && !errors.Is(err, errTemporary) {
+			t.Fatalf("iteration %d: unexpected error %v", i, err)
+		}
+		if dropped := thread.DroppedEvents(); dropped != 0 {
+			t.Fatalf("iteration %d: %d events dropped", i, dropped)
+		}
+		starts := 0
+		for len(events) > 0 {
+			event := <-events
+			if event.State != RUNNING {
+				continue
+			}
+			starts++
+			if event.Time.After(stoppedAt) {
+				t.Fatalf("iteration %d: restarted after stop", i)
+			}
+		}
+		if runs := int(runnable.runs.Load()); runs > starts {
+			t.Fatalf("iteration %d: %d runs for %d attempts", i, runs, starts)
+		}
+	}
+}
+
+func TestIsRestarting(t *testing.T) {
+	runnable := &failOnceRunnable{}
+	thread := New(runnable, WithAutoRestart(-1, 50*time.Millisecond))
+	if thread.IsRestarting() {
+		t.Fatal("expected a new thread not to be restarting")
+	}
+	thread.Start()
+	eventually(t, thread.IsRestarting)
+	if delay := thread.RestartDelay(); delay != 50*time.Millisecond {
+		t.Fatalf("expected a restart delay of 50ms, got %v", delay)
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected state RUNNING during the backoff, got %d", state)
+	}
+	eventually(t, func() bool { return runnable.runs.Load() == 2 })
+	if thread.IsRestarting() || thread.RestartDelay() != 0 {
+		t.Fatal("expected restart to have completed")
+	}
+	thread.StopAndJoin()
+}
+
+func TestRestartOnCleanExit(t *testing.T) {
+	// task mode: a clean exit means the work is done
+	task := &countRunnable{}
+	thread := New(task, WithAutoRestart(-1, time.Millisecond), WithRestartOnCleanExit(false))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs := task.runs.Load(); runs != 1 {
+		t.Fatalf("expected a single run in task mode, got %d", runs)
+	}
+	// daemon mode: a clean exit is restarted until the limit is reached
+	daemon := &countRunnable{}
+	thread = New(daemon, WithAutoRestart(3, time.Millisecond), WithRestartOnCleanExit(true))
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs := daemon.runs.Load(); runs != 4 {
+		t.Fatalf("expected 1 run and 3 restarts in daemon mode, got %d runs", runs)
+	}
+	// a stopped daemon is not restarted
+	thread = New(&blockingRunnable{}, WithAutoRestart(-1, time.Millisecond), WithRestartOnCleanExit(true))
+	thread.Start()
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package thread
+
+import (
+	"time"
+)
+
+// StartAt schedules the Thread to be started at the given time. A previously
+// scheduled start is replaced. The schedule can be cancelled using CancelStart
+// or Stop.
+func (t *Thread) StartAt(at time.Time) {
+	t.StartAfter(time.Until(at))
+}
+
+// StartAfter schedules the Thread to be started after the given duration. A
+// previously scheduled start is replaced. The schedule can be cancelled using
+// CancelStart or Stop.
+func (t *Thread) StartAfter(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.cancelStartLocked()
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		t.mutex.Lock()
+		// check that this schedule has not been cancelled or replaced meanwhile
+		if t.startTimer != timer {
+			t.mutex.Unlock()
+			return
+		}
+		t.startTimer = nil
+		t.mutex.Unlock()
+		t.start(false)
+	})
+	t.startTimer = timer
+}
+
+// CancelStart cancels a start scheduled via StartAt or StartAfter. It returns
+// true if a pending start was cancelled and false if none was pending.
+func (t *Thread) CancelStart() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.cancelStartLocked()
+}
+
+// Internal helper tearing down the start timer, must be called with the mutex
+// held
+func (t *Thread) cancelStartLocked() bool {
+	if t.startTimer == nil {
+		return false
+	}
+	t.startTimer.Stop()
+	t.startTimer = nil
+	return true
+}
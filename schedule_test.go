@@ -0,0 +1,37 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartAfter(t *testing.T) {
+	runnable := &countRunnable{}
+	thread := New(runnable)
+	thread.StartAfter(10 * time.Millisecond)
+	eventually(t, func() bool { return runnable.runs.Load() == 1 })
+	thread.Join()
+	if thread.CancelStart() {
+		t.Fatal("expected nothing to cancel after the scheduled start")
+	}
+}
+
+func TestCancelStart(t *testing.T) {
+	runnable := &countRunnable{}
+	thread := New(runnable)
+	thread.StartAt(time.Now().Add(20 * time.Millisecond))
+	if !thread.CancelStart() {
+		t.Fatal("expected pending start to be cancelled")
+	}
+	if thread.CancelStart() {
+		t.Fatal("expected nothing to cancel twice")
+	}
+
+	thread.StartAfter(20 * time.Millisecond)
+	thread.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if runs := runnable.runs.Load(); runs != 0 {
+		t.Fatalf("expected cancelled runnable never to run, got %d runs", runs)
+	}
+}
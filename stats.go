@@ -0,0 +1,229 @@
+package thread
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Stats is a point-in-time view of a Thread for debugging and monitoring.
+type Stats struct {
+	Name       string
+	InstanceID uint64
+	State      State
+	Uptime     time.Duration // time since Start(), zero if stopped
+	LastError  error
+	Labels     map[string]string // see WithLabels
+}
+
+// Stats returns a consistent snapshot of the Thread's statistics.
+func (t *Thread) Stats() Stats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := Stats{
+		Name:       t.name,
+		InstanceID: t.instanceID,
+		State:      t.state,
+		LastError:  t.err,
+		Labels:     copyLabels(t.labels),
+	}
+	if t.state != STOPPED {
+		stats.Uptime = time.Since(t.started)
+	}
+	return stats
+}
+
+// Status returns a one-line human readable summary of the Thread for quick
+// debugging, e.g. "worker: RUNNING uptime=12s starts=2 lastErr=<nil>". The
+// number of starts counts calls to Start() and RunBlocking() since creation.
+// Labels are appended if there are any, e.g. "labels={region=eu,team=core}".
+func (t *Thread) Status() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	name := t.name
+	if name == "" {
+		name = "<unnamed>"
+	}
+	var uptime time.Duration
+	if t.state != STOPPED {
+		uptime = time.Since(t.started).Truncate(time.Millisecond)
+	}
+	status := fmt.Sprintf("%s: %s uptime=%v starts=%d lastErr=%v", name, t.state, uptime, t.starts, t.err)
+	if len(t.labels) > 0 {
+		status += " labels=" + formatLabels(t.labels)
+	}
+	return status
+}
+
+// StartCount returns the number of starts via Start() and RunBlocking() since
+// the Thread was created, see RunCount for the number of runs.
+func (t *Thread) StartCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.starts
+}
+
+// RunCount returns the number of times the Runnable has been run since the
+// Thread was created, including automatic restarts and replacements, so a
+// single start may account for many runs.
+func (t *Thread) RunCount() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.runs
+}
+
+// JoinN blocks until the Runnable has been run at least n times, as reported by
+// RunCount, and returns true, or returns false once the timeout elapsed first,
+// e.g. to make tests of automatic restarts deterministic. Runs count from the
+// creation of the Thread and JoinN keeps waiting while the Thread is stopped,
+// as it may be started again.
+func (t *Thread) JoinN(n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		t.mutex.Lock()
+		runs, begun := t.runs, t.begun
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		t.mutex.Unlock()
+		if runs >= n {
+			return true
+		}
+		select {
+		case <-begun:
+			// the current run has begun already, wait for the next one
+			begun = nil
+		default:
+		}
+		select {
+		case <-begun:
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// Result summarizes the outcome of a Thread for post-mortem analysis, much
+// like the exit status of a process.
+type Result struct {
+	Ran      bool          // whether the Thread has ever run, all other fields are zero otherwise
+	Err      error         // the error returned by Join()
+	Reason   ExitReason    // why the most recent run ended
+	Duration time.Duration // time from Start() until the end of the most recent run
+	Starts   int           // number of starts since creation
+}
+
+// Result returns the outcome of the most recent lifecycle of the Thread. It is
+// meant to be called after the Thread terminated, while it is running the
+// fields describe the most recent run that ended.
+func (t *Thread) Result() Result {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.runID == 0 {
+		return Result{}
+	}
+	result := Result{Ran: true, Err: t.err, Reason: t.exitReason, Starts: t.starts}
+	if !t.runStopped.IsZero() {
+		result.Duration = t.runStopped.Sub(t.started)
+	}
+	return result
+}
+
+// RunSummary describes a single run of a Thread, see RecentRuns.
+type RunSummary struct {
+	RunID  uint64
+	Start  time.Time
+	End    time.Time
+	Err    error // the error of the run, as reported by LastError()
+	Reason ExitReason
+}
+
+// WithRunHistory keeps summaries of the n most recent runs of the Thread,
+// including automatic restarts, for post-incident analysis, see RecentRuns.
+// The history spans all starts of the Thread. It is disabled by default.
+func WithRunHistory(n int) Option {
+	return func(t *Thread) {
+		t.history = nil
+		t.historyNext = 0
+		if n > 0 {
+			t.history = make([]RunSummary, 0, n)
+		}
+	}
+}
+
+// RecentRuns returns the summaries of the most recent runs, oldest first, or
+// nil if the history has not been enabled via WithRunHistory. Runs killed via
+// Kill are included with ExitKilled, runs skipped during a restart backoff are
+// not.
+func (t *Thread) RecentRuns() []RunSummary {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.history == nil {
+		return nil
+	}
+	runs := make([]RunSummary, 0, len(t.history))
+	runs = append(runs, t.history[t.historyNext:]...)
+	return append(runs, t.history[:t.historyNext]...)
+}
+
+// Internal helper adding the current run to the history, overwriting the oldest
+// entry once it is full, must be called with the mutex held
+func (t *Thread) recordRunLocked() {
+	if t.history == nil {
+		return
+	}
+	run := RunSummary{RunID: t.runID, Start: t.runStarted, End: t.runStopped, Err: t.err, Reason: t.exitReason}
+	if len(t.history) < cap(t.history) {
+		t.history = append(t.history, run)
+		return
+	}
+	t.history[t.historyNext] = run
+	t.historyNext = (t.historyNext + 1) % len(t.history)
+}
+
+// Registry of threads included in Snapshot
+var registry struct {
+	mutex   sync.Mutex
+	threads []*Thread
+}
+
+// Register adds the Thread to the package level registry, making it part of
+// Snapshot. Registering a Thread twice has no effect.
+func Register(t *Thread) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for _, registered := range registry.threads {
+		if registered == t {
+			return
+		}
+	}
+	registry.threads = append(registry.threads, t)
+}
+
+// Unregister removes the Thread from the package level registry.
+func Unregister(t *Thread) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for i, registered := range registry.threads {
+		if registered == t {
+			registry.threads = append(registry.threads[:i], registry.threads[i+1:]...)
+			return
+		}
+	}
+}
+
+// Snapshot returns the Stats of all registered threads in registration order,
+// e.g. for a debug endpoint.
+func Snapshot() []Stats {
+	registry.mutex.Lock()
+	threads := append([]*Thread(nil), registry.threads...)
+	registry.mutex.Unlock()
+	snapshot := make([]Stats, 0, len(threads))
+	for _, t := range threads {
+		snapshot = append(snapshot, t.Stats())
+	}
+	return snapshot
+}
@@ -0,0 +1,157 @@
+package thread
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	running := New(&blockingRunnable{}, WithName("running"))
+	stopped := New(&blockingRunnable{}, WithName("stopped"))
+	Register(running)
+	Register(stopped)
+	Register(running)
+	defer Unregister(running)
+	defer Unregister(stopped)
+	running.Start()
+	defer func() {
+		running.Stop()
+		running.Join()
+	}()
+
+	snapshot := Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("expected 2 registered threads, got %d", len(snapshot))
+	}
+	if snapshot[0].Name != "running" || snapshot[0].State != RUNNING {
+		t.Fatalf("expected first thread to be running, got %+v", snapshot[0])
+	}
+	if snapshot[1].Name != "stopped" || snapshot[1].State != STOPPED || snapshot[1].Uptime != 0 {
+		t.Fatalf("expected second thread to be stopped, got %+v", snapshot[1])
+	}
+
+	Unregister(stopped)
+	if snapshot := Snapshot(); len(snapshot) != 1 || snapshot[0].Name != "running" {
+		t.Fatalf("expected only the running thread after unregistering, got %+v", snapshot)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	thread := New(&blockingRunnable{}, WithName("worker-3"))
+	if status := thread.Status(); status != "worker-3: STOPPED uptime=0s starts=0 lastErr=<nil>" {
+		t.Fatalf("unexpected status of a new thread: %q", status)
+	}
+	thread.Start()
+	thread.StopAndJoin()
+	thread.Start()
+	defer thread.StopAndJoin()
+	time.Sleep(5 * time.Millisecond)
+	status := thread.Status()
+	for _, field := range []string{"worker-3: RUNNING", "uptime=", "starts=2", "lastErr=<nil>"} {
+		if !strings.Contains(status, field) {
+			t.Fatalf("expected status to contain %q, got %q", field, status)
+		}
+	}
+	if strings.Contains(status, "uptime=0s") {
+		t.Fatalf("expected a non-zero uptime, got %q", status)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for state, expected := range map[State]string{RUNNING: "RUNNING", STOPPING: "STOPPING", STOPPED: "STOPPED", State(7): "State(7)"} {
+		if s := state.String(); s != expected {
+			t.Errorf("expected %q, got %q", expected, s)
+		}
+	}
+}
+
+func TestResult(t *testing.T) {
+	thread := New(&sleepRunnable{duration: 10 * time.Millisecond})
+	if result := thread.Result(); result.Ran || result != (Result{}) {
+		t.Fatalf("expected a zero result before the first run, got %+v", result)
+	}
+	thread.Start()
+	thread.Join()
+	result := thread.Result()
+	if !result.Ran || result.Err != nil || result.Reason != ExitCompleted || result.Starts != 1 || result.Duration < 10*time.Millisecond {
+		t.Fatalf("unexpected result of a clean run %+v", result)
+	}
+
+	thread = New(&returnRunnable{err: errTemporary})
+	thread.Start()
+	thread.Join()
+	thread.Start()
+	thread.Join()
+	result = thread.Result()
+	if !result.Ran || !errors.Is(result.Err, errTemporary) || result.Reason != ExitError || result.Starts != 2 {
+		t.Fatalf("unexpected result of a failed run %+v", result)
+	}
+}
+
+func TestRecentRuns(t *testing.T) {
+	if runs := New(&blockingRunnable{}).RecentRuns(); runs != nil {
+		t.Fatalf("expected no history by default, got %v", runs)
+	}
+	runnable := &countRunnable{errs: []error{errTemporary, errTemporary, errTemporary, errTemporary}}
+	thread := New(runnable, WithAutoRestart(-1, time.Millisecond), WithRunHistory(3))
+	thread.Start()
+	thread.Join()
+	runs := thread.RecentRuns()
+	if len(runs) != 3 {
+		t.Fatalf("expected the last 3 of 5 runs, got %d", len(runs))
+	}
+	for i, run := range runs {
+		if id := uint64(i + 3); run.RunID != id {
+			t.Fatalf("expected run %d at index %d, got %d", id, i, run.RunID)
+		}
+		if run.Start.IsZero() || run.End.Before(run.Start) {
+			t.Fatalf("expected run %d to have a valid time span, got %v to %v", run.RunID, run.Start, run.End)
+		}
+	}
+	if !errors.Is(runs[1].Err, errTemporary) || runs[1].Reason != ExitError {
+		t.Fatalf("expected failed run, got %v (%v)", runs[1].Err, runs[1].Reason)
+	}
+	if runs[2].Err != nil || runs[2].Reason != ExitCompleted {
+		t.Fatalf("expected completed run, got %v (%v)", runs[2].Err, runs[2].Reason)
+	}
+}
+
+func TestRunCount(t *testing.T) {
+	runnable := &countRunnable{errs: []error{errTemporary, errTemporary}}
+	thread := New(runnable, WithAutoRestart(-1, time.Millisecond))
+	if thread.StartCount() != 0 || thread.RunCount() != 0 {
+		t.Fatal("expected no starts and runs before the first start")
+	}
+	thread.Start()
+	thread.Join()
+	if n := thread.StartCount(); n != 1 {
+		t.Fatalf("expected 1 start, got %d", n)
+	}
+	if n := thread.RunCount(); n != 3 {
+		t.Fatalf("expected 3 runs including restarts, got %d", n)
+	}
+}
+
+func TestJoinN(t *testing.T) {
+	errs := make([]error, 10)
+	for i := range errs {
+		errs[i] = errTemporary
+	}
+	runnable := &countRunnable{errs: errs}
+	thread := New(runnable, WithAutoRestart(-1, 5*time.Millisecond))
+	if thread.JoinN(1, 10*time.Millisecond) {
+		t.Fatal("expected no runs before the start")
+	}
+	thread.Start()
+	defer thread.StopAndJoin()
+	if !thread.JoinN(3, time.Second) {
+		t.Fatalf("expected 3 runs, got %d", thread.RunCount())
+	}
+	// the run has begun, the call of the runnable follows right away
+	eventually(t, func() bool { return runnable.runs.Load() >= 3 })
+	if thread.JoinN(100, 20*time.Millisecond) {
+		t.Fatal("expected JoinN to time out")
+	}
+}
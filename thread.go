@@ -4,8 +4,13 @@ Package thread provides a "Thread"-like convenience wrapper around goroutines.
 package thread
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // State type determines a Thread's execution status
@@ -17,113 +22,1101 @@ const (
 	STOPPED
 )
 
+func (s State) String() string {
+	switch s {
+	case RUNNING:
+		return "RUNNING"
+	case STOPPING:
+		return "STOPPING"
+	case STOPPED:
+		return "STOPPED"
+	}
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
 var (
 	ErrAlreadyInitialized = errors.New("Thread has already been initialized")
 	ErrAlreadyStarted     = errors.New("Thread has already been started")
 	ErrMalfunction        = errors.New("Thread state is broken")
+	ErrStopped            = errors.New("Thread has been stopped")
+	ErrNotInitialized     = errors.New("Thread has not been initialized")
+	ErrNilRunnable        = errors.New("Thread has a nil Runnable")
+	ErrReadyTimeout       = errors.New("Thread did not become ready in time")
+	ErrStoppedBeforeReady = errors.New("Thread stopped before becoming ready")
+	ErrNotStarted         = errors.New("Thread has not been started")
+	ErrNotMember          = errors.New("Thread is not a member of the Group")
+	ErrMaxRuntime         = errors.New("Thread exceeded its maximum runtime")
+	ErrKilled             = errors.New("Thread has been killed")
+	ErrStopTimeout        = errors.New("Thread did not stop within its stop timeout")
 )
 
+// Source of the IDs returned by Thread.InstanceID()
+var instanceIDs atomic.Uint64
+
+// Default size of the buffer backing the channel returned by Thread.Events()
+const eventBufferSize = 16
+
+// Event describes a single state transition of a Thread.
+type Event struct {
+	State State
+	Time  time.Time
+	RunID uint64 // ID of the run the transition belongs to
+}
+
 // The Thread struct is neither a kernel nor a user thread implementation.
 // All it actually does is executing a goroutine and providing means to start
 // and stop it. Call it thread-like if you like.
+//
+// A Thread must not be copied after first use, always pass it by pointer.
 type Thread struct {
-	mutex        sync.Mutex
-	initialized  bool
-	state        State
-	stopRunnable chan bool
-	waitThread   chan bool
-	runnable     Runnable
+	noCopy             noCopy
+	mutex              sync.Mutex
+	initialized        bool
+	state              State
+	stopRunnable       chan bool
+	waitThread         chan struct{}
+	runnable           Runnable
+	events             chan Event
+	eventBuffer        int
+	overflow           OverflowPolicy
+	dropped            uint64
+	recoverLimit       int
+	panics             int
+	exitReason         ExitReason
+	changed            chan struct{}
+	err                error
+	panicHandler       func(recovered interface{}) error
+	ctx                context.Context
+	cancel             context.CancelCauseFunc
+	replace            bool
+	onError            func(err error)
+	onPanic            func(recovered interface{}, stack []byte)
+	onStopped          func(err error)
+	afterStop          func(err error)
+	autoRestart        bool
+	maxRestarts        int
+	backoff            time.Duration
+	strategy           BackoffStrategy
+	restarts           int
+	failures           int
+	minRunTime         time.Duration
+	runID              uint64
+	stopTimeout        time.Duration
+	stopTimer          *time.Timer
+	stopExpired        chan struct{}
+	quiesced           bool
+	starts             int
+	logger             Logger
+	logThrottle        errorThrottle
+	opts               []Option
+	inBackoff          bool
+	backoffDelay       time.Duration
+	instanceID         uint64
+	watchdogGrace      time.Duration
+	goroutine          uint64
+	stateChangedAt     time.Time
+	onStateChange      func(old, new State)
+	stateChanges       []stateChange
+	dispatching        bool
+	history            []RunSummary
+	historyNext        int
+	cpus               []int
+	inflight           int
+	draining           bool
+	idle               chan struct{}
+	runs               int
+	onReady            func()
+	begun              chan struct{}
+	restartOnCleanExit bool
+	labels             map[string]string
+	cleanupOrder       CleanupOrder
+	stopRequested      time.Time
+	outcome            *error
+	yieldBudget        int
+	desired            bool
+	reconciling        bool
+	strict             bool
+	classifier         func(err error) bool
+	ready              chan struct{}
+	name               string
+	metrics            MetricsSink
+	startTimer         *time.Timer
+	maxRuntime         time.Duration
+	runtimeTimer       *time.Timer
+	started            time.Time
+	runStarted         time.Time
+	runStopped         time.Time
 }
 
+// noCopy may be embedded into structs which must not be copied after first
+// use. It has no effect at runtime, but go vet's copylocks check reports any
+// value copy of a struct containing it.
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
 // Runnable is a simple interface describing a minimalistic runnable type
 // consisting of a main loop and using a for-select to determine when a
 // stop is expected:
 //
-//   for {
-//     select {
-//     case <-stop:
-//       return nil
-//     default:
-//       // do work
-//     }
-//   }
+//	for {
+//	  select {
+//	  case <-stop:
+//	    return nil
+//	  default:
+//	    // do work
+//	  }
+//	}
 //
 // See the package example for details how to implement such a runnable.
 type Runnable interface {
 	Run(stop chan bool) error
 }
 
-// New creates a new Thread and initializes it with the given Runnable.
-// Must be started separately using Thread.Start()
-func New(runnable Runnable) *Thread {
-	return (&Thread{}).Init(runnable)
+// Option configures a Thread, see New() and the With* functions.
+type Option func(*Thread)
+
+// New creates a new Thread and initializes it with the given Runnable and
+// options. Must be started separately using Thread.Start()
+// Panics with ErrNilRunnable if runnable is nil, see TryNew.
+func New(runnable Runnable, opts ...Option) *Thread {
+	return (&Thread{}).Init(runnable, opts...)
+}
+
+// TryNew is like New, but returns ErrNilRunnable instead of panicking if
+// runnable is nil.
+func TryNew(runnable Runnable, opts ...Option) (*Thread, error) {
+	if runnable == nil {
+		return nil, ErrNilRunnable
+	}
+	return New(runnable, opts...), nil
+}
+
+// Default options applied by Init, see SetDefaults
+var defaults struct {
+	mutex sync.Mutex
+	opts  []Option
 }
 
-// Init initializes the Thread with the given Runnable.
-// Panics with ErrAlreadyInitialized if it has been initialized before.
-func (t *Thread) Init(runnable Runnable) *Thread {
+// SetDefaults registers options applied to every Thread initialized afterwards,
+// before the options passed to New or Init, which therefore take precedence.
+// Each call replaces the previously registered defaults, calling it without
+// options clears them. It is safe for concurrent use, but meant to be called
+// once during program initialization, as Threads created concurrently may or
+// may not see the new defaults.
+func SetDefaults(opts ...Option) {
+	defaults.mutex.Lock()
+	defer defaults.mutex.Unlock()
+	defaults.opts = append([]Option(nil), opts...)
+}
+
+// Init initializes the Thread with the given Runnable and options.
+// Panics with ErrAlreadyInitialized if it has been initialized before and with
+// ErrNilRunnable if runnable is nil.
+func (t *Thread) Init(runnable Runnable, opts ...Option) *Thread {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 	// check state, if already initialized, panic
 	if t.initialized {
 		panic(ErrAlreadyInitialized)
 	}
+	// fail early rather than in the goroutine of the first run
+	if runnable == nil {
+		panic(ErrNilRunnable)
+	}
 	// set initial field values
 	t.initialized = true
 	t.state = STOPPED
+	t.stateChangedAt = time.Now()
 	t.runnable = runnable
+	t.ctx = context.Background()
+	t.metrics = noopMetrics{}
+	t.eventBuffer = eventBufferSize
+	t.instanceID = instanceIDs.Add(1)
+	t.opts = opts
+	defaults.mutex.Lock()
+	defaultOpts := defaults.opts
+	defaults.mutex.Unlock()
+	for _, opt := range defaultOpts {
+		opt(t)
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.labelMetricsLocked()
 	return t
 }
 
+// Internal helper creating a new Thread for the given Runnable with the same
+// configuration as t
+func (t *Thread) derive(runnable Runnable) *Thread {
+	t.mutex.Lock()
+	opts, ctx, stopTimeout := t.opts, t.ctx, t.stopTimeout
+	t.mutex.Unlock()
+	derived := New(runnable, opts...)
+	derived.ctx = ctx
+	derived.stopTimeout = stopTimeout
+	return derived
+}
+
+// WithName sets a name identifying the Thread in metrics, logs and errors.
+func WithName(name string) Option {
+	return func(t *Thread) {
+		t.name = name
+	}
+}
+
+// InstanceID returns the ID assigned to the Thread on initialization. IDs are
+// unique within the process and never change, so unlike the pointer they can
+// be used to identify the Thread in logs or serialized data.
+func (t *Thread) InstanceID() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.instanceID
+}
+
+// Name returns the name of the Thread, or an empty string if none was set.
+func (t *Thread) Name() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.name
+}
+
+// WithPanicHandler sets the function that is called with the recovered value
+// whenever the Runnable panics. The error it returns is stored for Join(). A
+// handler that panics itself lets the panic propagate and crash the program.
+// Without a handler, panics are wrapped into a PanicError.
+func WithPanicHandler(handler func(recovered interface{}) error) Option {
+	return func(t *Thread) {
+		t.panicHandler = handler
+	}
+}
+
+// WithOnError sets a hook which is called with the error returned by the
+// Runnable after each failed run. It is not called for panics, see
+// WithOnPanic, but for diagnostics of the stop watchdog, see WithStopWatchdog.
+// The hook is called outside of the Thread's mutex.
+func WithOnError(hook func(err error)) Option {
+	return func(t *Thread) {
+		t.onError = hook
+	}
+}
+
+// WithOnPanic sets a hook which is called with the recovered value and the
+// stack trace whenever the Runnable panics. It is called after the panic
+// handler and outside of the Thread's mutex.
+func WithOnPanic(hook func(recovered interface{}, stack []byte)) Option {
+	return func(t *Thread) {
+		t.onPanic = hook
+	}
+}
+
+// WithOnStopped sets a hook which is called exactly once per run after it
+// reached STOPPED, passing the result of the run. With automatic restarts it
+// is called for every completed run. The hook is called outside of the
+// Thread's mutex.
+func WithOnStopped(hook func(err error)) Option {
+	return func(t *Thread) {
+		t.onStopped = hook
+	}
+}
+
+// WithAfterStop sets a hook which is called once the Thread terminated, after
+// its final run and the hooks of that run, passing the error Join() returns. By
+// default the hook is guaranteed to complete before Join() returns, so it may
+// release resources observers of Join() depend on, see WithCleanupOrder. Note
+// that State() already reports STOPPED while the hook runs. It is not called
+// if the Thread is killed. The hook is called outside of the Thread's mutex.
+func WithAfterStop(hook func(err error)) Option {
+	return func(t *Thread) {
+		t.afterStop = hook
+	}
+}
+
+// CleanupOrder determines when observers of a terminated Thread are released
+// relative to the hooks of its final run, see WithCleanupOrder.
+type CleanupOrder uint8
+
+const (
+	// CloseAfterHooks releases Join() and Done() only once the hooks of the
+	// final run, including WithAfterStop, completed, so observers see the full
+	// cleanup, e.g. a hook signaling other systems
+	CloseAfterHooks CleanupOrder = iota
+	// CloseBeforeHooks releases Join() and Done() as soon as the final run ended
+	// and calls the hooks afterwards for minimal shutdown latency. The hooks may
+	// then still be running while the Thread is started again.
+	CloseBeforeHooks
+)
+
+// WithCleanupOrder chooses whether Join() and Done() unblock before or after
+// the hooks of the final run: the WithOnError, WithOnPanic and WithOnStopped
+// hooks, the metrics of the run and the WithAfterStop hook. The default is
+// CloseAfterHooks.
+func WithCleanupOrder(order CleanupOrder) Option {
+	return func(t *Thread) {
+		t.cleanupOrder = order
+	}
+}
+
+// WithMaxRuntime limits the total runtime of the Thread. Once d has elapsed
+// after Start(), the Thread is stopped as if Stop() was called, with the
+// context cause being ErrMaxRuntime. The budget spans all automatic restarts.
+func WithMaxRuntime(d time.Duration) Option {
+	return func(t *Thread) {
+		t.maxRuntime = d
+	}
+}
+
+// Internal helper starting the timer enforcing the maximum runtime, must be
+// called with the mutex held
+func (t *Thread) startRuntimeTimerLocked() {
+	if t.maxRuntime <= 0 {
+		return
+	}
+	done := t.waitThread
+	t.runtimeTimer = time.AfterFunc(t.maxRuntime, func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		// only stop the lifecycle the timer has been started for
+		if t.waitThread == done && t.state == RUNNING {
+			t.stopLocked(ErrMaxRuntime)
+		}
+	})
+}
+
+// Internal helper stopping the timer enforcing the maximum runtime, must be
+// called with the mutex held
+func (t *Thread) stopRuntimeTimerLocked() {
+	if t.runtimeTimer != nil {
+		t.runtimeTimer.Stop()
+		t.runtimeTimer = nil
+	}
+}
+
+// SetStopTimeout limits how long Join() waits for the Thread to terminate once
+// it has been signaled to stop, be it via Stop(), its context or its maximum
+// runtime. If the Runnable takes longer, Join() returns ErrStopTimeout while the
+// Runnable may keep running. Zero, the default, means waiting forever.
+func (t *Thread) SetStopTimeout(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopTimeout = d
+}
+
+// WithStopTimeout sets the initial stop timeout, see SetStopTimeout().
+func WithStopTimeout(d time.Duration) Option {
+	return func(t *Thread) {
+		t.stopTimeout = d
+	}
+}
+
+// WithStrictMode makes invalid lifecycle calls loud to catch logic bugs early:
+// Start() on a Thread which is not stopped panics with ErrAlreadyStarted and
+// Stop() on a Thread which is not running, including one already stopping,
+// panics with ErrNotRunning. StopAndJoin and StartAndWaitReady are checked
+// likewise. Bulk and background operations, such as those of Group,
+// StopToken, StopChain or scheduled starts, stay lenient, as they routinely
+// meet Threads in any state. By default all calls are lenient.
+func WithStrictMode() Option {
+	return func(t *Thread) {
+		t.strict = true
+	}
+}
+
+// Internal helper starting the stop timeout once the lifecycle is being
+// stopped, must be called with the mutex held
+func (t *Thread) startStopTimerLocked() {
+	if t.stopTimeout <= 0 || t.stopTimer != nil {
+		return
+	}
+	expired := t.stopExpired
+	t.stopTimer = time.AfterFunc(t.stopTimeout, func() {
+		close(expired)
+	})
+}
+
+// Internal helper stopping the stop timeout, must be called with the mutex held
+func (t *Thread) stopStopTimerLocked() {
+	if t.stopTimer != nil {
+		t.stopTimer.Stop()
+		t.stopTimer = nil
+	}
+}
+
+// Validate checks whether the Thread is ready to be started without actually
+// starting it and reports the optional interfaces its Runnable implements, see
+// Capabilities. It returns ErrNotInitialized if it is not, a nil Runnable is
+// already rejected by Init and ReplaceRunnable.
+func (t *Thread) Validate() (Capabilities, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.initialized {
+		return Capabilities{}, ErrNotInitialized
+	}
+	return capabilitiesOf(t.runnable), nil
+}
+
 // Start starts the Thread in a new goroutine and initializes its signal channels.
-func (t *Thread) Start() {
+// It returns the Thread for chaining, e.g. New(r).Start().Await(timeout).
+// Starting a Thread which is not stopped has no effect, in strict mode it
+// panics with ErrAlreadyStarted, see WithStrictMode.
+func (t *Thread) Start() *Thread {
+	t.start(true)
+	return t
+}
+
+// Internal helper starting the Thread unless it is running already, which is a
+// violation in strict mode if strict is set. It reports whether it started.
+func (t *Thread) start(strict bool) bool {
 	// check if already running
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 	if t.state != STOPPED {
-		return
+		if strict && t.strict {
+			panic(ErrAlreadyStarted)
+		}
+		return false
 	}
+	// launch new goroutine
+	go t.run(t.startLocked())
+	return true
+}
+
+// TryStart starts the Thread like Start, but reports why it could not be
+// started: ErrNotInitialized if it has not been initialized and
+// ErrAlreadyStarted if it is not stopped.
+func (t *Thread) TryStart() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.initialized {
+		return ErrNotInitialized
+	}
+	if t.state != STOPPED {
+		return ErrAlreadyStarted
+	}
+	go t.run(t.startLocked())
+	return nil
+}
+
+// MustStart starts the Thread like TryStart, but panics with the error if it
+// could not be started, for init-time wiring where this is fatal anyway. It
+// returns the Thread for chaining, e.g. thread := New(r).MustStart().
+func (t *Thread) MustStart() *Thread {
+	if err := t.TryStart(); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// RunBlocking runs the Thread like Start, but in the calling goroutine, and
+// returns the result of the run like Join. Stop() may be called from other
+// goroutines as usual. It returns ErrAlreadyStarted if the Thread is not
+// stopped.
+func (t *Thread) RunBlocking() error {
+	t.mutex.Lock()
+	if t.state != STOPPED {
+		t.mutex.Unlock()
+		return ErrAlreadyStarted
+	}
+	handle := t.startLocked()
+	t.mutex.Unlock()
+	t.run(handle)
+	// the Thread may have been killed and started again meanwhile, so report
+	// the result of this lifecycle rather than the current one
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return *handle.outcome
+}
+
+// Internal handle of a single run, passed to the goroutine executing it
+type runHandle struct {
+	ctx      context.Context
+	runnable Runnable
+	stop     chan bool
+	ready    chan struct{}
+	onReady  func()
+	begun    chan struct{}
+	// wait channel and final error of the lifecycle the run belongs to
+	done    chan struct{}
+	outcome *error
+}
+
+// Internal helper reporting whether the run has been detached from the Thread
+// by Kill, must be called with the mutex held
+func (h runHandle) detached() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Internal helper setting up a new lifecycle of the Thread, must be called
+// with the mutex held
+func (t *Thread) startLocked() runHandle {
 	// setup signal channels and update state to running
+	t.waitThread = make(chan struct{})
+	t.outcome = new(error)
+	t.stopExpired = make(chan struct{})
+	t.starts++
+	t.draining = false
+	t.stopRequested = time.Time{}
+	t.err = nil
+	t.restarts = 0
+	t.inBackoff = false
+	t.resetBackoffLocked()
+	t.panics = 0
+	t.dropped = 0
+	t.started = time.Now()
+	t.runStarted = t.started
+	t.runStopped = time.Time{}
+	handle := t.beginRunLocked()
+	t.startRuntimeTimerLocked()
+	return handle
+}
+
+// Internal helper setting up the stop channel and context of a new run and
+// updating the state to running, must be called with the mutex held
+func (t *Thread) beginRunLocked() runHandle {
 	t.stopRunnable = make(chan bool)
-	t.waitThread = make(chan bool)
-	t.state = RUNNING
-	// launch new goroutine
-	go t.run()
+	t.ready = make(chan struct{})
+	t.begun = make(chan struct{})
+	ctx, cancel := context.WithCancelCause(t.ctx)
+	t.cancel = cancel
+	t.runID++
+	t.setState(RUNNING)
+	return runHandle{
+		ctx:      ctx,
+		runnable: t.runnable,
+		stop:     t.stopRunnable,
+		ready:    t.ready,
+		onReady:  t.onReady,
+		begun:    t.begun,
+		done:     t.waitThread,
+		outcome:  t.outcome,
+	}
+}
+
+// Internal helper cleaning up after a run and storing its result, must be
+// called with the mutex held
+func (t *Thread) endRunLocked(err error, reason ExitReason) {
+	t.cancel(nil)
+	// in case we haven't been stopped, the channel is still open, so close it
+	// and pass through STOPPING, so that every run emits the same sequence
+	if t.state != STOPPING {
+		close(t.stopRunnable)
+		t.setState(STOPPING)
+	}
+	t.exitReason = reason
+	t.err = nil
+	if err != nil {
+		t.err = &ThreadError{Name: t.name, Reason: reason, Attempt: t.restarts + 1, RunID: t.runID, InstanceID: t.instanceID, Err: err}
+	}
+	// runOnce resets the end of the previous run, a run skipped during its
+	// restart backoff keeps it
+	if t.runStopped.IsZero() {
+		t.runStopped = time.Now()
+	}
 }
 
 // Internal helper function for running then cleaning up
-func (t *Thread) run() {
-	defer func() {
+func (t *Thread) run(handle runHandle) {
+	var err error
+	var delay time.Duration
+	restarting := false
+	for {
+		// wait for the restart backoff and the global start rate limit, a stop
+		// during the wait or right before the restart skips the run and keeps the
+		// error of the previous run
+		ran := (delay == 0 || Sleep(handle.stop, delay)) && awaitStartSlot(handle.stop) && t.beginAttempt(handle, restarting)
+		var panicked *recoveredPanic
+		if ran {
+			panicked, err = t.runOnce(handle)
+		}
+		requested := ran && errors.Is(err, ErrRestart)
+		if requested {
+			err = nil
+		}
+		restartable := ran && t.restartable(err)
+		t.mutex.Lock()
+		if handle.detached() {
+			// killed, the Thread has been cleaned up already
+			t.mutex.Unlock()
+			return
+		}
+		stopped := t.state == STOPPING
+		reason := ExitCompleted
+		switch {
+		case panicked != nil:
+			reason = ExitPanic
+			t.panics++
+		case err != nil:
+			reason = ExitError
+		case stopped:
+			reason = ExitStopped
+		}
+		t.endRunLocked(err, reason)
+		if ran {
+			t.recordRunLocked()
+		}
+		t.setState(STOPPED)
+		if ran && err == nil {
+			if !t.shortRunLocked() {
+				t.resetBackoffLocked()
+			} else if t.autoRestart {
+				// returned too quickly, back off as if the run had failed
+				restartable = true
+			}
+			if t.autoRestart && t.restartOnCleanExit {
+				// daemon mode, returning at all is unexpected
+				restartable = true
+			}
+		}
+		next, restart := false, false
+		if t.replace {
+			// a replacement has been requested, so continue with the new runnable
+			t.replace = false
+			next, delay = true, 0
+		} else if requested && !stopped {
+			// the runnable asked to be restarted
+			next, delay = true, 0
+			restart = true
+		} else if restartable && !stopped && !t.recoverLimitReachedLocked() {
+			next, delay = t.restartLocked()
+			restart = next
+		}
+		result := runResult{err: err, panicked: panicked, duration: t.runStopped.Sub(t.runStarted), restart: restart}
+		restarting = restart
+		t.inBackoff, t.backoffDelay = restart && delay > 0, delay
+		final, afterStop := t.err, t.afterStop
+		closeEarly := !next && t.cleanupOrder == CloseBeforeHooks
+		if next {
+			handle = t.beginRunLocked()
+		} else {
+			*handle.outcome = final
+			t.stopRuntimeTimerLocked()
+			t.stopStopTimerLocked()
+		}
+		t.mutex.Unlock()
+		if closeEarly {
+			// release observers right away, the hooks follow
+			close(handle.done)
+		}
+		if ran {
+			t.afterRun(result)
+		}
+		if !next {
+			if afterStop != nil {
+				afterStop(final)
+			}
+			// close wait thread in case anyone is listening, by default only once
+			// all hooks completed
+			if !closeEarly {
+				close(handle.done)
+			}
+			return
+		}
+	}
+}
+
+// Internal summary of a finished run
+type runResult struct {
+	err      error
+	panicked *recoveredPanic
+	duration time.Duration
+	restart  bool
+}
+
+// Internal helper reporting a finished run to the hooks and metrics, which are
+// called outside of the mutex
+func (t *Thread) afterRun(result runResult) {
+	t.mutex.Lock()
+	name, metrics, onError, onPanic, onStopped := t.name, t.metrics, t.onError, t.onPanic, t.onStopped
+	logger := t.logger
+	var lines []string
+	if result.err != nil {
+		lines = t.errorLogLinesLocked(result.err, time.Now())
+	}
+	t.mutex.Unlock()
+	for _, line := range lines {
+		logger.Printf("%s", line)
+	}
+	metrics.RecordStop(name, result.duration)
+	if result.err != nil {
+		metrics.RecordError(name, result.err)
+	}
+	if result.restart {
+		metrics.RecordRestart(name)
+	}
+	if result.panicked != nil && onPanic != nil {
+		onPanic(result.panicked.value, result.panicked.stack)
+	} else if result.panicked == nil && result.err != nil && onError != nil {
+		onError(result.err)
+	}
+	if onStopped != nil {
+		onStopped(result.err)
+	}
+}
+
+// Internal helper marking the start of an attempt. It returns false if the
+// Thread has been killed or, for automatic restarts, stopped in the meantime,
+// in which case no new attempt must be started.
+func (t *Thread) beginAttempt(handle runHandle, restarting bool) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.inBackoff = false
+	if handle.detached() || (restarting && t.state != RUNNING) {
+		return false
+	}
+	t.runs++
+	close(handle.begun)
+	t.runStarted = time.Now()
+	t.runStopped = time.Time{}
+	t.goroutine = currentGoroutine()
+	return true
+}
+
+// Internal helper executing a single run, which is stopped early if its
+// context is done
+func (t *Thread) runOnce(handle runHandle) (*recoveredPanic, error) {
+	t.mutex.Lock()
+	name, metrics, cpus := t.name, t.metrics, t.cpus
+	t.mutex.Unlock()
+	metrics.RecordStart(name)
+	if len(cpus) > 0 {
+		unpin, err := pinCPUs(cpus)
+		if err != nil {
+			return nil, err
+		}
+		defer unpin()
+	}
+	stopAfterFunc := context.AfterFunc(handle.ctx, func() {
 		t.mutex.Lock()
 		defer t.mutex.Unlock()
-		// in case we haven't been stopped, the channel is still open, so close it
-		if t.state != STOPPING {
-			close(t.stopRunnable)
+		if t.state == RUNNING && t.stopRunnable == handle.stop {
+			t.stopLocked(context.Cause(handle.ctx))
+		}
+	})
+	defer stopAfterFunc()
+	return t.call(handle)
+}
+
+// Internal helper calling the Runnable, panics are recorded and passed to the
+// panic handler
+func (t *Thread) call(handle runHandle) (panicked *recoveredPanic, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicked = &recoveredPanic{value: recovered, stack: debug.Stack()}
+			t.mutex.Lock()
+			handler := t.panicHandler
+			t.mutex.Unlock()
+			if handler == nil {
+				handler = defaultPanicHandler
+			}
+			err = handler(recovered)
 		}
-		// indicate state change and close wait thread in case anyone is listening
-		t.state = STOPPED
-		close(t.waitThread)
 	}()
-	// run child
-	t.runnable.Run(t.stopRunnable)
+	ready := readyFunc(handle.ready, handle.onReady)
+	if readyRunnable, ok := handle.runnable.(ReadyRunnable); ok {
+		return nil, readyRunnable.RunReady(handle.stop, ready)
+	}
+	// other runnables are considered ready as soon as they run
+	ready()
+	if contextRunnable, ok := handle.runnable.(ContextRunnable); ok {
+		return nil, contextRunnable.RunContext(handle.ctx)
+	}
+	return nil, handle.runnable.Run(handle.stop)
 }
 
 // Stop the Thread by signaling the Runnable to stop, effectively resulting in the target goroutine to exit.
 // To wait for the Thread to finish use Thread.Join().
+//
+// The Runnable may call Stop on its own Thread as well. The mutex is never held
+// while the Runnable runs, so this cannot deadlock, and the Runnable observes
+// its stop channel being closed like for any other caller.
+//
+// Stopping a Thread which is not running has no effect, in strict mode it
+// panics with ErrNotRunning, see WithStrictMode.
 func (t *Thread) Stop() {
+	t.stop(true)
+}
+
+// Internal helper stopping the Thread if it is running, stopping it otherwise
+// is a violation in strict mode if strict is set
+func (t *Thread) stop(strict bool) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	if !t.requestStopLocked() && strict && t.strict {
+		panic(ErrNotRunning)
+	}
+}
+
+// Internal helper stopping the Thread on behalf of a caller, like Stop, and
+// reporting whether it was running, must be called with the mutex held
+func (t *Thread) requestStopLocked() bool {
+	// a stop overrides a pending replacement of the runnable and start
+	t.replace = false
+	t.cancelStartLocked()
 	// check state, stopping twice is useless, so simply return
 	if t.state != RUNNING {
-		return
+		return false
 	}
-	t.state = STOPPING
+	t.stopLocked(ErrStopped)
+	return true
+}
+
+// Internal helper to stop the current run with the given context cause, must
+// be called with the mutex held and the state being RUNNING
+func (t *Thread) stopLocked(cause error) {
+	t.setState(STOPPING)
 	// signal the runnable to stop
+	t.cancel(cause)
 	close(t.stopRunnable)
+	t.startWatchdogLocked()
+	if !t.replace {
+		t.stopRequested = time.Now()
+		t.startStopTimerLocked()
+	}
+}
+
+// StopAndJoin stops the Thread and waits for it to terminate, see Stop() and
+// Join().
+func (t *Thread) StopAndJoin() error {
+	t.Stop()
+	return t.Join()
+}
+
+// Kill signals the Runnable to stop like Stop, but marks the Thread as STOPPED
+// right away without waiting for the Runnable to return. Join returns
+// ErrKilled immediately and the Thread may be started again.
+//
+// This is meant for emergency shutdowns only: the goroutine of the killed run
+// may keep running and leak if the Runnable does not observe its stop signal.
+// Once it returns, its result is discarded and no hooks are called for it.
+func (t *Thread) Kill() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.replace = false
+	t.cancelStartLocked()
+	switch t.state {
+	case STOPPED:
+		return
+	case RUNNING:
+		t.stopLocked(ErrKilled)
+	}
+	t.stopRuntimeTimerLocked()
+	t.stopStopTimerLocked()
+	t.exitReason = ExitKilled
+	t.err = &ThreadError{Name: t.name, Reason: ExitKilled, Attempt: t.restarts + 1, RunID: t.runID, InstanceID: t.instanceID, Err: ErrKilled}
+	if !t.inBackoff {
+		t.runStopped = time.Now()
+		t.recordRunLocked()
+	}
+	t.setState(STOPPED)
+	*t.outcome = t.err
+	// detaches the goroutine of the run, see runHandle.detached
+	close(t.waitThread)
+}
+
+// ReplaceRunnable swaps the Runnable of the Thread. If the Thread is running,
+// the current run is stopped and, once it has returned, the new Runnable is run
+// in its place without State() ever reporting STOPPED in between. Join
+// therefore keeps blocking across the replacement. A concurrent Stop() takes
+// precedence and cancels the pending restart.
+func (t *Thread) ReplaceRunnable(runnable Runnable) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.initialized {
+		return ErrNotInitialized
+	}
+	if runnable == nil {
+		return ErrNilRunnable
+	}
+	t.runnable = runnable
+	// the new runnable has not been quiesced
+	t.quiesced = false
+	if t.state == RUNNING {
+		t.replace = true
+		t.stopLocked(ErrStopped)
+	}
+	return nil
 }
 
-// Join blocks until the Thread terminates.
-func (t *Thread) Join() {
+// Done returns a channel which is closed once the current lifecycle of the
+// Thread terminated, at the same time Join() unblocks. It returns nil if the
+// Thread has never been started. A later Start() begins a new lifecycle with a
+// new channel.
+func (t *Thread) Done() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.waitThread
+}
+
+// Started returns a channel which is closed once the Runnable of the current run
+// has actually begun executing, e.g. to select on the start of a worker. Each
+// run, including automatic restarts, gets a new channel, which is never closed
+// if the run is skipped, e.g. due to a stop during its restart backoff. It
+// returns nil if the Thread has never been started.
+func (t *Thread) Started() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.begun
+}
+
+// Join blocks until the Thread terminates and returns the error of its most
+// recent run, which is either the error returned by the Runnable or the result
+// of the panic handler, wrapped into a *ThreadError. If a stop timeout is set,
+// see SetStopTimeout(), and exceeded, Join returns ErrStopTimeout instead.
+// All hooks of the final run, including WithAfterStop, have completed by the
+// time Join returns, unless WithCleanupOrder(CloseBeforeHooks) is set.
+// Join may be called from any number of goroutines concurrently, all of which
+// observe the same error as it is only read under the mutex once the Thread
+// has terminated.
+func (t *Thread) Join() error {
+	t.mutex.Lock()
+	done, expired := t.waitThread, t.stopExpired
+	t.mutex.Unlock()
 	// wait until runnable has exited
-	<-t.waitThread
+	select {
+	case <-done:
+	case <-expired:
+		select {
+		case <-done:
+		default:
+			return ErrStopTimeout
+		}
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+// Events returns the channel on which the Thread publishes its state
+// transitions. All callers share the same channel, so concurrent receivers
+// compete for events.
+//
+// Every run emits a RUNNING event first, which marks the beginning of a new
+// lifecycle. A Start/Stop/Start/Stop cycle thus produces exactly:
+//
+//	RUNNING, STOPPING, STOPPED, RUNNING, STOPPING, STOPPED
+//
+// This also holds if the Runnable returns on its own without Stop being
+// called, the STOPPING event is emitted nonetheless. If a run is followed by
+// another one without the Thread being started again, e.g. due to an automatic
+// restart, its STOPPED event is directly followed by the next RUNNING event.
+// Events are buffered, see WithEventBuffer. If the buffer is full because
+// nobody is receiving, events are dropped rather than blocking the Thread.
+func (t *Thread) Events() <-chan Event {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.events == nil {
+		t.events = make(chan Event, t.eventBuffer)
+	}
+	return t.events
+}
+
+// State returns the current execution status of the Thread.
+func (t *Thread) State() State {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.state
+}
+
+// StateChangedAt returns the time of the most recent state transition, or of
+// the initialization if the Thread has never been started. Together with
+// State() this tells for how long the Thread has been in its current state,
+// e.g. to detect stuck shutdowns.
+func (t *Thread) StateChangedAt() time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.stateChangedAt
+}
+
+// IsStopping reports whether the Thread has been asked to stop but its
+// Runnable has not returned yet.
+func (t *Thread) IsStopping() bool {
+	return t.State() == STOPPING
+}
+
+// Internal helper returning the current state and a channel which is closed
+// on the next state transition
+func (t *Thread) watchState() (State, <-chan struct{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.changed == nil {
+		t.changed = make(chan struct{})
+	}
+	return t.state, t.changed
+}
+
+// WaitState blocks until the Thread reaches the given state or the timeout
+// elapses and reports whether the state was reached. Waiting for STOPPING is
+// also satisfied by STOPPED, as the Thread may pass STOPPING quickly.
+func (t *Thread) WaitState(state State, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		current, changed := t.watchState()
+		if current == state || (state == STOPPING && current == STOPPED) {
+			return true
+		}
+		// wait for the next transition, then check again
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// CurrentRunID returns the ID of the current or most recent run. IDs start at
+// 1 and increase with every run of the Thread, including automatic restarts
+// and replacements, so they can be used to correlate log lines with a specific
+// run. Zero means the Thread has never run.
+func (t *Thread) CurrentRunID() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.runID
+}
+
+// LastError returns the error of the most recent run without waiting for the
+// Thread to terminate, or nil if the run succeeded or the current lifecycle has
+// not produced an error yet. With automatic restarts this is the error which
+// triggered the most recent restart. The error is wrapped into a *ThreadError.
+func (t *Thread) LastError() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.err
+}
+
+// LastRunDuration returns how long the most recent run took. It returns false
+// if the Thread has never run or is currently running.
+func (t *Thread) LastRunDuration() (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.runStopped.IsZero() {
+		return 0, false
+	}
+	return t.runStopped.Sub(t.runStarted), true
+}
+
+// Internal helper to update the state and publish the transition, must be
+// called with the mutex held
+func (t *Thread) setState(state State) {
+	old := t.state
+	t.state = state
+	t.stateChangedAt = time.Now()
+	// wake up everyone waiting for a state change
+	if t.changed != nil {
+		close(t.changed)
+		t.changed = nil
+	}
+	if t.events != nil {
+		t.emitLocked(Event{State: state, Time: t.stateChangedAt, RunID: t.runID})
+	}
+	if t.onStateChange != nil {
+		t.queueStateChangeLocked(old, state)
+	}
 }
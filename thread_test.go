@@ -0,0 +1,739 @@
+package thread
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eventually fails the test if cond does not become true within a second
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingRunnable runs until it is told to stop
+type blockingRunnable struct{}
+
+func (r *blockingRunnable) Run(stop chan bool) error {
+	<-stop
+	return nil
+}
+
+func TestEventsAcrossRestart(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	events := thread.Events()
+
+	for i := 0; i < 2; i++ {
+		thread.Start()
+		thread.Stop()
+		thread.Join()
+	}
+
+	expected := []State{RUNNING, STOPPING, STOPPED, RUNNING, STOPPING, STOPPED}
+	for i, state := range expected {
+		select {
+		case event := <-events:
+			if event.State != state {
+				t.Fatalf("event %d: expected state %d, got %d", i, state, event.State)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d: timed out waiting for state %d", i, state)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected trailing event with state %d", event.State)
+	default:
+	}
+}
+
+// The noCopy guard is only observable through go vet. Adding the following
+// line to any function makes vet fail with "assignment copies lock value":
+//
+//	copied := *New(&blockingRunnable{})
+func TestNoCopyGuard(t *testing.T) {
+	var locker sync.Locker = &New(&blockingRunnable{}).noCopy
+	// both methods are no-ops and must never block
+	locker.Lock()
+	locker.Lock()
+	locker.Unlock()
+}
+
+func TestWaitState(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	thread.Start()
+	if !thread.WaitState(RUNNING, time.Second) {
+		t.Fatal("expected thread to be running")
+	}
+	if thread.WaitState(STOPPED, 10*time.Millisecond) {
+		t.Fatal("expected wait for STOPPED to time out while running")
+	}
+
+	go thread.Stop()
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected thread to reach STOPPED after Stop()")
+	}
+	if state := thread.State(); state != STOPPED {
+		t.Fatalf("expected state STOPPED, got %d", state)
+	}
+	// STOPPING has been passed already
+	if !thread.WaitState(STOPPING, 0) {
+		t.Fatal("expected wait for STOPPING to be satisfied by STOPPED")
+	}
+}
+
+// sleepRunnable sleeps for the given duration, ignoring any stop signal
+type sleepRunnable struct {
+	duration time.Duration
+}
+
+func (r *sleepRunnable) Run(stop chan bool) error {
+	time.Sleep(r.duration)
+	return nil
+}
+
+func TestLastRunDuration(t *testing.T) {
+	thread := New(&sleepRunnable{duration: 50 * time.Millisecond})
+	if _, ok := thread.LastRunDuration(); ok {
+		t.Fatal("expected no duration before the first run")
+	}
+	thread.Start()
+	if _, ok := thread.LastRunDuration(); ok {
+		t.Fatal("expected no duration while running")
+	}
+	thread.Join()
+
+	duration, ok := thread.LastRunDuration()
+	if !ok {
+		t.Fatal("expected a duration after the run")
+	}
+	if duration < 50*time.Millisecond || duration > 500*time.Millisecond {
+		t.Fatalf("expected duration of roughly 50ms, got %v", duration)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if _, err := (&Thread{}).Validate(); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got %v", err)
+	}
+	caps, err := New(&readyHealthRunnable{}).Validate()
+	if err != nil {
+		t.Fatalf("expected valid thread, got %v", err)
+	}
+	if expected := (Capabilities{Readiness: true, Health: true}); caps != expected {
+		t.Fatalf("expected %+v, got %+v", expected, caps)
+	}
+}
+
+func TestNewNil(t *testing.T) {
+	defer func() {
+		if recovered := recover(); recovered != ErrNilRunnable {
+			t.Fatalf("expected New(nil) to panic with ErrNilRunnable, got %v", recovered)
+		}
+	}()
+	New(nil)
+}
+
+func TestTryNew(t *testing.T) {
+	if thread, err := TryNew(nil); thread != nil || err != ErrNilRunnable {
+		t.Fatalf("expected ErrNilRunnable, got %v", err)
+	}
+	thread, err := TryNew(&blockingRunnable{}, WithName("worker"))
+	if err != nil || thread.Name() != "worker" {
+		t.Fatalf("expected a named thread, got %v", err)
+	}
+}
+
+// signalRunnable closes started once it runs, then runs until stopped
+type signalRunnable struct {
+	started chan struct{}
+}
+
+func (r *signalRunnable) Run(stop chan bool) error {
+	close(r.started)
+	<-stop
+	return nil
+}
+
+func TestReplaceRunnable(t *testing.T) {
+	first := &signalRunnable{started: make(chan struct{})}
+	second := &signalRunnable{started: make(chan struct{})}
+	thread := New(first)
+	thread.Start()
+	<-first.started
+
+	if err := thread.ReplaceRunnable(second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-second.started:
+	case <-time.After(time.Second):
+		t.Fatal("expected replacement runnable to be run")
+	}
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected thread to keep running, got state %d", state)
+	}
+
+	thread.Stop()
+	thread.Join()
+	if err := (&Thread{}).ReplaceRunnable(second); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got %v", err)
+	}
+}
+
+// returnRunnable returns the given error immediately
+type returnRunnable struct {
+	err error
+}
+
+func (r *returnRunnable) Run(stop chan bool) error {
+	return r.err
+}
+
+// releaseRunnable ignores the stop signal until released
+type releaseRunnable struct {
+	release chan struct{}
+	err     error
+}
+
+func (r *releaseRunnable) Run(stop chan bool) error {
+	<-r.release
+	return r.err
+}
+
+func TestIsStopping(t *testing.T) {
+	runnable := &releaseRunnable{release: make(chan struct{})}
+	thread := New(runnable)
+	thread.Start()
+	if thread.IsStopping() {
+		t.Fatal("expected running thread not to be stopping")
+	}
+	thread.Stop()
+	if !thread.IsStopping() {
+		t.Fatal("expected thread to be stopping until the runnable returns")
+	}
+	close(runnable.release)
+	thread.Join()
+	if thread.IsStopping() {
+		t.Fatal("expected stopped thread not to be stopping")
+	}
+}
+
+func TestStoppingEventOnReturn(t *testing.T) {
+	thread := New(&returnRunnable{})
+	events := thread.Events()
+	thread.Start()
+	thread.Join()
+
+	for i, state := range []State{RUNNING, STOPPING, STOPPED} {
+		if event := <-events; event.State != state {
+			t.Fatalf("event %d: expected state %d, got %d", i, state, event.State)
+		}
+	}
+}
+
+func TestMaxRuntime(t *testing.T) {
+	thread := New(&blockingRunnable{}, WithMaxRuntime(30*time.Millisecond))
+	begin := time.Now()
+	thread.Start()
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected thread to stop itself")
+	}
+	if elapsed := time.Since(begin); elapsed < 30*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Fatalf("expected thread to stop after roughly 30ms, took %v", elapsed)
+	}
+}
+
+func TestMaxRuntimeSpansRestarts(t *testing.T) {
+	runnable := &countRunnable{errs: make([]error, 1000)}
+	for i := range runnable.errs {
+		runnable.errs[i] = errTemporary
+	}
+	thread := New(runnable, WithAutoRestart(-1, 5*time.Millisecond), WithMaxRuntime(50*time.Millisecond))
+	thread.Start()
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected restarting thread to stop after its maximum runtime")
+	}
+	if runs := runnable.runs.Load(); runs < 2 {
+		t.Fatalf("expected multiple runs within the budget, got %d", runs)
+	}
+}
+
+func TestRunBlocking(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	result := make(chan error)
+	go func() {
+		result <- thread.RunBlocking()
+	}()
+	if !thread.WaitState(RUNNING, time.Second) {
+		t.Fatal("expected thread to be running")
+	}
+	if err := thread.RunBlocking(); err != ErrAlreadyStarted {
+		t.Fatalf("expected ErrAlreadyStarted, got %v", err)
+	}
+	thread.Stop()
+	if err := <-result; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := New(&returnRunnable{err: errTemporary}).RunBlocking(); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected error of the run, got %v", err)
+	}
+}
+
+func TestMustStart(t *testing.T) {
+	thread := New(&blockingRunnable{}).MustStart()
+	if !thread.WaitState(RUNNING, time.Second) {
+		t.Fatal("expected thread to be running")
+	}
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != ErrAlreadyStarted {
+				t.Fatalf("expected panic with ErrAlreadyStarted, got %v", recovered)
+			}
+		}()
+		thread.MustStart()
+	}()
+	thread.Stop()
+	thread.Join()
+	if err := (&Thread{}).TryStart(); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got %v", err)
+	}
+}
+
+func TestRunBlockingKilled(t *testing.T) {
+	runnable := &releaseRunnable{release: make(chan struct{})}
+	thread := New(runnable)
+	result := make(chan error)
+	go func() {
+		result <- thread.RunBlocking()
+	}()
+	if !thread.WaitState(RUNNING, time.Second) {
+		t.Fatal("expected thread to be running")
+	}
+	thread.Kill()
+	// a new lifecycle must not hold up the killed one
+	thread.Start()
+	defer thread.StopAndJoin()
+	close(runnable.release)
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrKilled) {
+			t.Fatalf("expected ErrKilled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected RunBlocking to return once the killed run returned")
+	}
+}
+
+func TestOnStopped(t *testing.T) {
+	stopped := make(chan error, 10)
+	thread := New(&blockingRunnable{}, WithOnStopped(func(err error) {
+		stopped <- err
+	}))
+	for i := 0; i < 2; i++ {
+		thread.Start()
+		thread.Stop()
+		thread.Join()
+		select {
+		case err := <-stopped:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected OnStopped to be called for run %d", i)
+		}
+	}
+
+	runnable := &countRunnable{errs: []error{errTemporary}}
+	thread = New(runnable,
+		WithAutoRestart(-1, time.Millisecond),
+		WithOnStopped(func(err error) {
+			stopped <- err
+		}),
+	)
+	thread.Start()
+	thread.Join()
+	for _, expected := range []error{errTemporary, nil} {
+		if err := <-stopped; err != expected {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(stopped) != 0 {
+		t.Fatalf("expected OnStopped exactly once per run, got %d extra calls", len(stopped))
+	}
+}
+
+// selfStopRunnable stops its own thread after the given number of iterations
+type selfStopRunnable struct {
+	thread     *Thread
+	iterations int
+}
+
+func (r *selfStopRunnable) Run(stop chan bool) error {
+	for i := 0; ; i++ {
+		if i == r.iterations {
+			r.thread.Stop()
+		}
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+	}
+}
+
+func TestSelfStop(t *testing.T) {
+	runnable := &selfStopRunnable{iterations: 3}
+	thread := New(runnable)
+	runnable.thread = thread
+	thread.Start()
+
+	done := make(chan error)
+	go func() {
+		done <- thread.Join()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected self-stopped thread to be joinable")
+	}
+	if state := thread.State(); state != STOPPED {
+		t.Fatalf("expected state STOPPED, got %d", state)
+	}
+}
+
+func TestKill(t *testing.T) {
+	slow := &releaseRunnable{release: make(chan struct{})}
+	thread := New(slow)
+	thread.Start()
+	thread.Kill()
+	if state := thread.State(); state != STOPPED {
+		t.Fatalf("expected state STOPPED right after Kill, got %d", state)
+	}
+	if err := thread.Join(); !errors.Is(err, ErrKilled) {
+		t.Fatalf("expected ErrKilled, got %v", err)
+	}
+
+	// the detached run must not interfere with a new one
+	thread.ReplaceRunnable(&blockingRunnable{})
+	thread.Start()
+	close(slow.release)
+	time.Sleep(10 * time.Millisecond)
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected new run to be unaffected by the killed one, got state %d", state)
+	}
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCurrentRunID(t *testing.T) {
+	thread := New(&countRunnable{errs: []error{errTemporary, errTemporary}}, WithAutoRestart(-1, time.Millisecond))
+	if id := thread.CurrentRunID(); id != 0 {
+		t.Fatalf("expected run ID 0 before start, got %d", id)
+	}
+	events := thread.Events()
+	thread.Start()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected final run to succeed, got %v", err)
+	}
+	var ids []uint64
+	for len(events) > 0 {
+		if event := <-events; event.State == RUNNING {
+			ids = append(ids, event.RunID)
+		}
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Fatalf("expected run IDs [1 2 3], got %v", ids)
+	}
+	if id := thread.CurrentRunID(); id != 3 {
+		t.Fatalf("expected current run ID 3, got %d", id)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	runnable := &releaseRunnable{release: make(chan struct{})}
+	thread := New(runnable, WithStopTimeout(20*time.Millisecond))
+	thread.Start()
+	if err := thread.StopAndJoin(); err != ErrStopTimeout {
+		t.Fatalf("expected ErrStopTimeout, got %v", err)
+	}
+	close(runnable.release)
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected thread to stop once released")
+	}
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected no error once stopped, got %v", err)
+	}
+}
+
+func TestStopTimeoutNotExceeded(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	thread.SetStopTimeout(time.Second)
+	thread.Start()
+	time.Sleep(20 * time.Millisecond)
+	if err := thread.StopAndJoin(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// failOnceRunnable fails its first run and blocks until stopped afterwards
+type failOnceRunnable struct {
+	runs atomic.Int32
+}
+
+func (r *failOnceRunnable) Run(stop chan bool) error {
+	if r.runs.Add(1) == 1 {
+		return errTemporary
+	}
+	<-stop
+	return nil
+}
+
+func TestLastError(t *testing.T) {
+	runnable := &failOnceRunnable{}
+	thread := New(runnable, WithAutoRestart(-1, time.Millisecond))
+	if err := thread.LastError(); err != nil {
+		t.Fatalf("expected no error before start, got %v", err)
+	}
+	thread.Start()
+	eventually(t, func() bool { return runnable.runs.Load() == 2 })
+	if state := thread.State(); state != RUNNING {
+		t.Fatalf("expected thread to be running, got state %d", state)
+	}
+	if err := thread.LastError(); !errors.Is(err, errTemporary) {
+		t.Fatalf("expected the error of the first run, got %v", err)
+	}
+	thread.Stop()
+	if err := thread.Join(); err != nil {
+		t.Fatalf("expected the final run to succeed, got %v", err)
+	}
+	if err := thread.LastError(); err != nil {
+		t.Fatalf("expected no error after the final run, got %v", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	sink := &recordingSink{}
+	strategy := ConstantBackoff{Delay: time.Millisecond}
+	thread := New(&blockingRunnable{},
+		WithName("worker"),
+		WithAutoRestart(3, time.Second),
+		WithBackoffStrategy(strategy),
+		WithMaxRuntime(time.Minute),
+		WithEventBuffer(4, DropOldest),
+		WithMetrics(sink),
+	)
+	if thread.Name() != "worker" {
+		t.Fatalf("expected name worker, got %q", thread.Name())
+	}
+	if !thread.autoRestart || thread.maxRestarts != 3 || thread.backoff != time.Second {
+		t.Fatal("expected auto restart to be configured")
+	}
+	if thread.strategy != strategy || thread.maxRuntime != time.Minute || thread.metrics != sink {
+		t.Fatal("expected backoff strategy, max runtime and metrics to be configured")
+	}
+	if cap(thread.Events()) != 4 || thread.overflow != DropOldest {
+		t.Fatal("expected event buffer to be configured")
+	}
+	// the single argument form keeps the defaults
+	if thread := New(&blockingRunnable{}); thread.autoRestart || cap(thread.Events()) != eventBufferSize {
+		t.Fatal("expected defaults without options")
+	}
+}
+
+func TestConcurrentJoin(t *testing.T) {
+	release := make(chan struct{})
+	thread := New(&releaseRunnable{release: release, err: errTemporary})
+	thread.Start()
+	errs := make([]error, 10)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = thread.Join()
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	if !errors.Is(errs[0], errTemporary) {
+		t.Fatalf("expected the error of the run, got %v", errs[0])
+	}
+	for i, err := range errs {
+		if err != errs[0] {
+			t.Fatalf("join %d: expected identical error %p, got %p", i, errs[0], err)
+		}
+	}
+}
+
+func TestAfterStop(t *testing.T) {
+	var cleaned atomic.Bool
+	var stopErr error
+	thread := New(&returnRunnable{err: errTemporary}, WithAfterStop(func(err error) {
+		time.Sleep(10 * time.Millisecond)
+		stopErr = err
+		cleaned.Store(true)
+	}))
+	thread.Start()
+	err := thread.Join()
+	if !cleaned.Load() {
+		t.Fatal("expected the after stop hook to complete before Join returned")
+	}
+	if stopErr != err || !errors.Is(err, errTemporary) {
+		t.Fatalf("expected the hook to receive the error of Join, got %v and %v", stopErr, err)
+	}
+}
+
+func TestCleanupOrder(t *testing.T) {
+	for _, order := range []CleanupOrder{CloseAfterHooks, CloseBeforeHooks} {
+		release := make(chan struct{})
+		var stopped, cleaned atomic.Bool
+		thread := New(&returnRunnable{},
+			WithCleanupOrder(order),
+			WithOnStopped(func(error) {
+				stopped.Store(true)
+			}),
+			WithAfterStop(func(error) {
+				if order == CloseBeforeHooks {
+					// observers are released without waiting for the hook
+					<-release
+				}
+				cleaned.Store(true)
+			}),
+		)
+		thread.Start()
+		<-thread.Done()
+		thread.Join()
+		switch order {
+		case CloseAfterHooks:
+			if !stopped.Load() || !cleaned.Load() {
+				t.Fatal("expected all hooks to complete before Join returned")
+			}
+		case CloseBeforeHooks:
+			if cleaned.Load() {
+				t.Fatal("expected Join to return before the after stop hook completed")
+			}
+			close(release)
+			eventually(t, cleaned.Load)
+		}
+	}
+}
+
+func TestInstanceID(t *testing.T) {
+	const count = 1000
+	ids := make(map[uint64]bool, count)
+	for i := 0; i < count; i++ {
+		thread := New(&blockingRunnable{})
+		id := thread.InstanceID()
+		if id == 0 || ids[id] {
+			t.Fatalf("expected a unique non-zero ID, got %d", id)
+		}
+		if thread.InstanceID() != id || thread.Stats().InstanceID != id {
+			t.Fatal("expected the ID to be stable")
+		}
+		ids[id] = true
+	}
+}
+
+func TestStateChangedAt(t *testing.T) {
+	before := time.Now()
+	thread := New(&blockingRunnable{})
+	initialized := thread.StateChangedAt()
+	if initialized.Before(before) {
+		t.Fatal("expected the initialization time")
+	}
+	thread.Start()
+	thread.WaitState(RUNNING, time.Second)
+	started := thread.StateChangedAt()
+	if started.Before(initialized) {
+		t.Fatal("expected the timestamp to advance on start")
+	}
+	time.Sleep(5 * time.Millisecond)
+	thread.StopAndJoin()
+	stopped := thread.StateChangedAt()
+	if stopped.Sub(started) < 5*time.Millisecond {
+		t.Fatalf("expected the timestamp to update on stop, advanced by %v", stopped.Sub(started))
+	}
+}
+
+func TestStarted(t *testing.T) {
+	thread := New(&blockingRunnable{})
+	if thread.Started() != nil {
+		t.Fatal("expected no channel before the first start")
+	}
+	for i := 0; i < 2; i++ {
+		thread.Start()
+		select {
+		case <-thread.Started():
+		case <-time.After(time.Second):
+			t.Fatal("expected channel to be closed once the run began")
+		}
+		thread.Stop()
+		thread.Join()
+	}
+	// a run skipped during its backoff never begins
+	runnable := &countRunnable{errs: []error{errTemporary}}
+	thread = New(runnable, WithAutoRestart(-1, time.Second))
+	thread.Start()
+	for !thread.IsRestarting() {
+		time.Sleep(time.Millisecond)
+	}
+	thread.Stop()
+	thread.Join()
+	select {
+	case <-thread.Started():
+		t.Fatal("expected skipped restart not to begin")
+	default:
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	violation := func(fn func()) (recovered interface{}) {
+		defer func() {
+			recovered = recover()
+		}()
+		fn()
+		return nil
+	}
+	thread := New(&blockingRunnable{}, WithStrictMode())
+	if recovered := violation(thread.Stop); recovered != ErrNotRunning {
+		t.Fatalf("expected stopping a new thread to panic with ErrNotRunning, got %v", recovered)
+	}
+	thread.Start()
+	if recovered := violation(func() { thread.Start() }); recovered != ErrAlreadyStarted {
+		t.Fatalf("expected starting a running thread to panic with ErrAlreadyStarted, got %v", recovered)
+	}
+	if recovered := violation(thread.Stop); recovered != nil {
+		t.Fatalf("expected a valid stop not to panic, got %v", recovered)
+	}
+	thread.Join()
+	if recovered := violation(func() { thread.StopAndJoin() }); recovered != ErrNotRunning {
+		t.Fatalf("expected stopping a stopped thread to panic with ErrNotRunning, got %v", recovered)
+	}
+	// bulk operations stay lenient
+	g := NewGroup()
+	g.Add(thread)
+	g.StopAll()
+	// the default is lenient
+	lenient := New(&blockingRunnable{})
+	lenient.Stop()
+	lenient.Start()
+	lenient.Start()
+	lenient.StopAndJoin()
+	lenient.Stop()
+}
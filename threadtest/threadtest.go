@@ -0,0 +1,47 @@
+/*
+Package threadtest provides assertions for testing code built on Threads.
+*/
+package threadtest
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ms-xy/thread"
+)
+
+// Interval in which the goroutine count is checked by AssertNoLeak
+const pollInterval = time.Millisecond
+
+// AssertStopped fails the test if th does not reach STOPPED within the given
+// duration. Otherwise it returns the result of th.Join(), so the caller can
+// check the error of the final run.
+func AssertStopped(t testing.TB, th *thread.Thread, within time.Duration) error {
+	t.Helper()
+	if !th.WaitState(thread.STOPPED, within) {
+		t.Fatalf("thread %q did not stop within %v: %s", th.Name(), within, th.Status())
+		return nil
+	}
+	return th.Join()
+}
+
+// AssertNoLeak fails the test if the number of goroutines does not drop to
+// baseline or below within the given duration. Capture the baseline with
+// runtime.NumGoroutine() before starting the Threads under test. As other
+// tests running in parallel affect the count, only use it in sequential tests.
+func AssertNoLeak(t testing.TB, baseline int, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines leaked", n-baseline)
+			return
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,102 @@
+package threadtest
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ms-xy/thread"
+)
+
+// recordingTB records failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// blockingRunnable runs until stopped
+type blockingRunnable struct{}
+
+func (r *blockingRunnable) Run(stop chan bool) error {
+	<-stop
+	return nil
+}
+
+// stubbornRunnable ignores its stop signal until released
+type stubbornRunnable struct {
+	release chan struct{}
+}
+
+func (r *stubbornRunnable) Run(stop chan bool) error {
+	<-r.release
+	return nil
+}
+
+// failingRunnable returns its error right away
+type failingRunnable struct {
+	err error
+}
+
+func (r *failingRunnable) Run(stop chan bool) error {
+	return r.err
+}
+
+func TestAssertStopped(t *testing.T) {
+	th := thread.New(&blockingRunnable{})
+	th.Start()
+	th.Stop()
+	recorder := &recordingTB{TB: t}
+	if err := AssertStopped(recorder, th, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recorder.failures) != 0 {
+		t.Fatalf("expected no failures, got %v", recorder.failures)
+	}
+}
+
+func TestAssertStoppedError(t *testing.T) {
+	errFailed := errors.New("failed")
+	th := thread.New(&failingRunnable{err: errFailed})
+	th.Start()
+	if err := AssertStopped(t, th, time.Second); !errors.Is(err, errFailed) {
+		t.Fatalf("expected the error of the run, got %v", err)
+	}
+}
+
+func TestAssertStoppedTimeout(t *testing.T) {
+	runnable := &stubbornRunnable{release: make(chan struct{})}
+	th := thread.New(runnable, thread.WithName("stubborn"))
+	th.Start()
+	th.Stop()
+	recorder := &recordingTB{TB: t}
+	AssertStopped(recorder, th, 10*time.Millisecond)
+	if len(recorder.failures) != 1 {
+		t.Fatalf("expected a failure, got %v", recorder.failures)
+	}
+	close(runnable.release)
+	th.Join()
+}
+
+func TestAssertNoLeak(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	th := thread.New(&blockingRunnable{})
+	th.Start()
+
+	recorder := &recordingTB{TB: t}
+	AssertNoLeak(recorder, baseline, 10*time.Millisecond)
+	if len(recorder.failures) != 1 {
+		t.Fatalf("expected the running thread to be reported, got %v", recorder.failures)
+	}
+
+	th.Stop()
+	AssertStopped(t, th, time.Second)
+	AssertNoLeak(t, baseline, time.Second)
+}
@@ -0,0 +1,48 @@
+package thread
+
+import (
+	"sync"
+)
+
+// StopToken stops a set of otherwise independent Threads at once, e.g. for a
+// global drain. It is a lightweight alternative to Group for coordinated
+// shutdown. The zero value is ready to use.
+type StopToken struct {
+	mutex     sync.Mutex
+	threads   []*Thread
+	triggered bool
+}
+
+// Register adds the Thread to the threads stopped by Trigger(). If the token
+// has already been triggered, the Thread is stopped right away.
+func (s *StopToken) Register(t *Thread) {
+	s.mutex.Lock()
+	triggered := s.triggered
+	if !triggered {
+		s.threads = append(s.threads, t)
+	}
+	s.mutex.Unlock()
+	if triggered {
+		t.stop(false)
+	}
+}
+
+// Trigger stops all registered Threads, use Join on them to wait for their
+// termination. Triggering a token more than once has no effect.
+func (s *StopToken) Trigger() {
+	s.mutex.Lock()
+	threads := s.threads
+	s.threads = nil
+	s.triggered = true
+	s.mutex.Unlock()
+	for _, t := range threads {
+		t.stop(false)
+	}
+}
+
+// Triggered reports whether Trigger() has been called.
+func (s *StopToken) Triggered() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.triggered
+}
@@ -0,0 +1,40 @@
+package thread
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopToken(t *testing.T) {
+	var token StopToken
+	first := New(&blockingRunnable{})
+	second := New(&blockingRunnable{})
+	first.Start()
+	second.Start()
+	token.Register(first)
+	token.Register(second)
+	if token.Triggered() {
+		t.Fatal("expected token not to be triggered")
+	}
+
+	token.Trigger()
+	if !token.Triggered() {
+		t.Fatal("expected token to be triggered")
+	}
+	for i, thread := range []*Thread{first, second} {
+		if err := thread.Join(); err != nil {
+			t.Fatalf("thread %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestStopTokenRegisterAfterTrigger(t *testing.T) {
+	var token StopToken
+	token.Trigger()
+	thread := New(&blockingRunnable{})
+	thread.Start()
+	token.Register(thread)
+	if !thread.WaitState(STOPPED, time.Second) {
+		t.Fatal("expected thread to be stopped on registration")
+	}
+}
@@ -0,0 +1,120 @@
+package thread
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// ErrStackUnavailable is returned by Thread.StackTrace if the stack of the
+// Runnable's goroutine could not be isolated.
+var ErrStackUnavailable = errors.New("Stack of the Runnable is unavailable")
+
+// StuckError is reported by the stop watchdog if a Runnable did not return
+// within the grace period after being signaled to stop, e.g. because it is
+// blocked sending to a full channel.
+type StuckError struct {
+	Name  string        // name of the Thread
+	Grace time.Duration // the grace period which elapsed
+	Stack []byte        // stack of the Runnable's goroutine at that time
+}
+
+func (e *StuckError) Error() string {
+	return fmt.Sprintf("Thread %q did not stop within %v:\n%s", e.Name, e.Grace, e.Stack)
+}
+
+// WithStopWatchdog enables a watchdog, which captures the stack of the
+// Runnable's goroutine if it does not return within grace after being signaled
+// to stop. The resulting *StuckError is passed to the WithOnError hook and the
+// logger, if set, to help debugging stuck shutdowns. The Thread keeps waiting
+// for the Runnable either way.
+func WithStopWatchdog(grace time.Duration) Option {
+	return func(t *Thread) {
+		t.watchdogGrace = grace
+	}
+}
+
+// Internal helper arming the watchdog for the current run, must be called with
+// the mutex held while stopping
+func (t *Thread) startWatchdogLocked() {
+	if t.watchdogGrace <= 0 {
+		return
+	}
+	stop, grace := t.stopRunnable, t.watchdogGrace
+	time.AfterFunc(grace, func() {
+		t.mutex.Lock()
+		stuck := t.state == STOPPING && t.stopRunnable == stop
+		name, goroutine, onError, logger := t.name, t.goroutine, t.onError, t.logger
+		t.mutex.Unlock()
+		if !stuck {
+			return
+		}
+		err := &StuckError{Name: name, Grace: grace, Stack: goroutineStack(goroutine)}
+		if onError != nil {
+			onError(err)
+		}
+		if logger != nil {
+			logger.Printf("%v", err)
+		}
+	})
+}
+
+// StackTrace returns the current stack trace of the goroutine executing the
+// Runnable, e.g. to find out where a worker is stuck without dumping all
+// goroutines of the process. It is best-effort: the goroutine is identified by
+// the ID recorded when the run began, taken from the traces of all goroutines.
+// It returns ErrNotRunning if the Thread is not running and
+// ErrStackUnavailable if the goroutine could not be found.
+func (t *Thread) StackTrace() ([]byte, error) {
+	t.mutex.Lock()
+	state, goroutine := t.state, t.goroutine
+	t.mutex.Unlock()
+	if state == STOPPED {
+		return nil, ErrNotRunning
+	}
+	if goroutine == 0 {
+		return nil, ErrStackUnavailable
+	}
+	stack := goroutineStack(goroutine)
+	if stack == nil {
+		return nil, ErrStackUnavailable
+	}
+	return stack, nil
+}
+
+// Internal helper returning the ID of the calling goroutine, parsed from its
+// stack trace as the runtime does not expose it otherwise
+func currentGoroutine() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// the trace begins with "goroutine <id> ["
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// Internal helper returning the stack trace of the goroutine with the given ID
+func goroutineStack(id uint64) []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, trace := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(trace, prefix) {
+			return trace
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package thread
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockedSendRunnable sends to out without observing its stop signal
+type blockedSendRunnable struct {
+	out chan int
+}
+
+func (r *blockedSendRunnable) Run(stop chan bool) error {
+	r.out <- 1
+	return nil
+}
+
+func TestStopWatchdog(t *testing.T) {
+	runnable := &blockedSendRunnable{out: make(chan int)}
+	reported := make(chan error, 1)
+	thread := New(runnable, WithName("sender"), WithStopWatchdog(20*time.Millisecond), WithOnError(func(err error) {
+		reported <- err
+	}))
+	thread.Start()
+	time.Sleep(10 * time.Millisecond)
+	thread.Stop()
+	select {
+	case err := <-reported:
+		var stuck *StuckError
+		if !errors.As(err, &stuck) {
+			t.Fatalf("expected a *StuckError, got %v", err)
+		}
+		if stuck.Name != "sender" || !bytes.Contains(stuck.Stack, []byte("blockedSendRunnable")) {
+			t.Fatalf("expected the stack of the blocked runnable, got:\n%s", stuck.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a diagnostic after the grace period")
+	}
+	<-runnable.out
+	if err := thread.Join(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStopWatchdogQuiet(t *testing.T) {
+	reported := make(chan error, 1)
+	thread := New(&blockingRunnable{}, WithStopWatchdog(10*time.Millisecond), WithOnError(func(err error) {
+		reported <- err
+	}))
+	thread.Start()
+	thread.StopAndJoin()
+	select {
+	case err := <-reported:
+		t.Fatalf("expected no diagnostic for a prompt stop, got %v", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	thread := New(&sleepRunnable{duration: 100 * time.Millisecond})
+	if _, err := thread.StackTrace(); err != ErrNotRunning {
+		t.Fatalf("expected ErrNotRunning before the start, got %v", err)
+	}
+	thread.Start()
+	time.Sleep(20 * time.Millisecond)
+	stack, err := thread.StackTrace()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(stack, []byte("sleepRunnable")) || !bytes.Contains(stack, []byte("time.Sleep")) {
+		t.Fatalf("expected the stack of the sleeping runnable, got:\n%s", stack)
+	}
+	thread.Join()
+	if _, err := thread.StackTrace(); err != ErrNotRunning {
+		t.Fatalf("expected ErrNotRunning after the run, got %v", err)
+	}
+}